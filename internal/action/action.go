@@ -0,0 +1,211 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/allow"
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+const (
+	// TypeRolloutRestart restarts the pods of a Deployment, StatefulSet or DaemonSet.
+	TypeRolloutRestart = "rollout-restart"
+	// TypeScale changes the replicas of an object eg. "scale:+2", "scale:-1" or "scale:3".
+	TypeScale = "scale"
+	// TypeSuspend suspends a CronJob or Job.
+	TypeSuspend = "suspend"
+
+	// AnnotationRestartedAt is the pod template annotation used by "kubectl rollout restart".
+	AnnotationRestartedAt = "kubectl.kubernetes.io/restartedAt"
+
+	// DefaultCooldown is the minimum time between actions on the same object.
+	DefaultCooldown = 15 * time.Minute
+)
+
+// Action is a remediation which is executed against a target object.
+type Action struct {
+	Type string
+	// Replicas is the number or change in number of replicas for a scale action.
+	Replicas int64
+	// Relative is true when Replicas is a change rather than an absolute number.
+	Relative bool
+}
+
+// Parse an action eg. "rollout-restart", "scale:+2" or "suspend".
+func Parse(value string) (Action, error) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(value), ":")
+
+	switch name {
+	case TypeRolloutRestart, TypeSuspend:
+		if arg != "" {
+			return Action{}, fmt.Errorf("%s does not take an argument", name)
+		}
+
+		return Action{Type: name}, nil
+	case TypeScale:
+		replicas, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return Action{}, fmt.Errorf("failed to parse replicas: %w", err)
+		}
+
+		return Action{
+			Type:     TypeScale,
+			Replicas: replicas,
+			Relative: strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-"),
+		}, nil
+	}
+
+	return Action{}, fmt.Errorf("unknown action: %s", value)
+}
+
+// String returns the action in the same format it was parsed from.
+func (a Action) String() string {
+	if a.Type != TypeScale {
+		return a.Type
+	}
+
+	if a.Relative {
+		return fmt.Sprintf("%s:%+d", a.Type, a.Replicas)
+	}
+
+	return fmt.Sprintf("%s:%d", a.Type, a.Replicas)
+}
+
+// Patch returns the merge patch which executes the action against the object, including the cooldown annotation.
+func (a Action) Patch(object *unstructured.Unstructured, now time.Time) (map[string]any, error) {
+	spec := map[string]any{}
+
+	switch a.Type {
+	case TypeRolloutRestart:
+		if _, found, _ := unstructured.NestedMap(object.Object, "spec", "template"); !found {
+			return nil, fmt.Errorf("%s/%s does not have a pod template", object.GetKind(), object.GetName())
+		}
+
+		spec["template"] = map[string]any{
+			"metadata": map[string]any{
+				"annotations": map[string]string{
+					AnnotationRestartedAt: now.UTC().Format(time.RFC3339),
+				},
+			},
+		}
+	case TypeScale:
+		current, found, err := unstructured.NestedInt64(object.Object, "spec", "replicas")
+		if err != nil || !found {
+			return nil, fmt.Errorf("%s/%s does not have replicas", object.GetKind(), object.GetName())
+		}
+
+		replicas := a.Replicas
+		if a.Relative {
+			replicas += current
+		}
+
+		if replicas < 0 {
+			replicas = 0
+		}
+
+		spec["replicas"] = replicas
+	case TypeSuspend:
+		if kind := object.GetKind(); kind != "CronJob" && kind != "Job" {
+			return nil, fmt.Errorf("%s/%s cannot be suspended", object.GetKind(), object.GetName())
+		}
+
+		spec["suspend"] = true
+	default:
+		return nil, fmt.Errorf("unknown action: %s", a.Type)
+	}
+
+	patch := Cooldown(now)
+	patch["spec"] = spec
+
+	return patch, nil
+}
+
+// Cooldown returns the merge patch which only records the cooldown annotation, so dry runs are cooled down as well.
+func Cooldown(now time.Time) map[string]any {
+	return map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{
+				annotation.KeyActionAt: now.UTC().Format(time.RFC3339),
+			},
+		},
+	}
+}
+
+// Rule permits actions on kinds in namespaces.
+type Rule struct {
+	// Namespaces are names or globs. Empty matches all namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Kinds are "group/Kind" globs eg. "apps/Deployment". Empty matches all kinds.
+	Kinds []string `json:"kinds,omitempty"`
+	// Actions are the permitted action types eg. "rollout-restart".
+	Actions []string `json:"actions"`
+}
+
+// Options for executing actions.
+type Options struct {
+	// Rules permit actions. Actions are denied when there are no rules.
+	Rules []Rule
+	// Cooldown is the minimum time between actions on the same object.
+	Cooldown time.Duration
+	// DryRun records the audit event without changing the object.
+	DryRun bool
+}
+
+// ParseRules from a JSON list.
+func ParseRules(rules string) ([]Rule, error) {
+	if rules == "" {
+		return nil, nil
+	}
+
+	var parsed []Rule
+
+	if err := json.Unmarshal([]byte(rules), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal action rules: %w", err)
+	}
+
+	return parsed, nil
+}
+
+// Allowed returns an error unless a rule permits the action on the object.
+func (o Options) Allowed(a Action, object metav1.Object, group, kind string) error {
+	for _, rule := range o.Rules {
+		if !allow.MatchNamespace(rule.Namespaces, object.GetNamespace()) || !allow.MatchKind(rule.Kinds, group, kind) {
+			continue
+		}
+
+		for _, permitted := range rule.Actions {
+			if permitted == a.Type {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("action %s is not permitted on %s/%s in namespace %s", a.Type, group, kind, object.GetNamespace())
+}
+
+// Cooling returns the time the last action was executed if the object is within the cooldown.
+func (o Options) Cooling(object metav1.Object, now time.Time) (time.Time, bool) {
+	value, ok := object.GetAnnotations()[annotation.KeyActionAt]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	last, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	cooldown := o.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+
+	return last, now.Sub(last) < cooldown
+}
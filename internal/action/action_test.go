@@ -0,0 +1,100 @@
+package action
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+func newDeployment(replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]any{
+				"namespace": "test",
+				"name":      "drupal",
+			},
+			"spec": map[string]any{
+				"replicas": replicas,
+				"template": map[string]any{},
+			},
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	for value, expected := range map[string]Action{
+		"rollout-restart": {Type: TypeRolloutRestart},
+		"suspend":         {Type: TypeSuspend},
+		"scale:+2":        {Type: TypeScale, Replicas: 2, Relative: true},
+		"scale:-1":        {Type: TypeScale, Replicas: -1, Relative: true},
+		"scale:3":         {Type: TypeScale, Replicas: 3},
+	} {
+		a, err := Parse(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, a)
+		assert.Equal(t, value, a.String())
+	}
+
+	for _, value := range []string{"delete", "scale", "scale:lots", "suspend:now"} {
+		_, err := Parse(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	patch, err := Action{Type: TypeScale, Replicas: 2, Relative: true}.Patch(newDeployment(3), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), patch["spec"].(map[string]any)["replicas"])
+	assert.Equal(t, "2024-01-01T12:00:00Z", patch["metadata"].(map[string]any)["annotations"].(map[string]string)[annotation.KeyActionAt])
+
+	patch, err = Action{Type: TypeScale, Replicas: -5, Relative: true}.Patch(newDeployment(3), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), patch["spec"].(map[string]any)["replicas"])
+
+	patch, err = Action{Type: TypeRolloutRestart}.Patch(newDeployment(1), now)
+	assert.NoError(t, err)
+	assert.Contains(t, patch["spec"], "template")
+
+	_, err = Action{Type: TypeSuspend}.Patch(newDeployment(1), now)
+	assert.Error(t, err)
+}
+
+func TestAllowed(t *testing.T) {
+	rules, err := ParseRules(`[{"namespaces": ["test"], "kinds": ["apps/Deployment"], "actions": ["rollout-restart"]}]`)
+	assert.NoError(t, err)
+
+	options := Options{Rules: rules}
+	deployment := newDeployment(1)
+
+	assert.NoError(t, options.Allowed(Action{Type: TypeRolloutRestart}, deployment, "apps", "Deployment"))
+	assert.Error(t, options.Allowed(Action{Type: TypeScale}, deployment, "apps", "Deployment"))
+	assert.Error(t, options.Allowed(Action{Type: TypeRolloutRestart}, deployment, "apps", "StatefulSet"))
+	assert.Error(t, Options{}.Allowed(Action{Type: TypeRolloutRestart}, deployment, "apps", "Deployment"))
+}
+
+func TestCooling(t *testing.T) {
+	var (
+		now        = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		deployment = newDeployment(1)
+		options    = Options{Cooldown: time.Hour}
+	)
+
+	_, ok := options.Cooling(deployment, now)
+	assert.False(t, ok)
+
+	deployment.SetAnnotations(map[string]string{annotation.KeyActionAt: "2024-01-01T11:30:00Z"})
+
+	_, ok = options.Cooling(deployment, now)
+	assert.True(t, ok)
+
+	_, ok = options.Cooling(deployment, now.Add(time.Hour))
+	assert.False(t, ok)
+}
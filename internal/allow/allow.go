@@ -0,0 +1,190 @@
+package allow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+// Wildcard is the cluster key for a policy which applies to clusters without their own policy.
+const Wildcard = "*"
+
+// ErrDenied is returned when a policy does not allow the alarm to write to the target.
+var ErrDenied = errors.New("denied by allowlist")
+
+// Policy restricts where alarms for a cluster can write events.
+type Policy struct {
+	// Namespaces are names or globs which the target namespace must match. Empty allows all namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// NamespaceSelector is a label selector which the target namespace must match.
+	NamespaceSelector string `json:"namespaceSelector,omitempty"`
+	// Kinds are "group/Kind" globs which the target and owners must match eg. "apps/*" or "Pod" for the core group.
+	// Empty allows all kinds.
+	Kinds []string `json:"kinds,omitempty"`
+	// RequireOwner requires the alarm's account or name prefix to match the namespace annotations.
+	RequireOwner bool `json:"requireOwner,omitempty"`
+
+	selector labels.Selector
+}
+
+// Checker determines if an alarm is allowed to write to a target.
+type Checker struct {
+	policies map[string]*Policy
+}
+
+// New creates a checker from a JSON object of policies keyed by cluster name.
+func New(policies string) (*Checker, error) {
+	checker := &Checker{}
+
+	if policies == "" {
+		return checker, nil
+	}
+
+	if err := json.Unmarshal([]byte(policies), &checker.policies); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal allowlist: %w", err)
+	}
+
+	for cluster, policy := range checker.policies {
+		selector, err := labels.Parse(policy.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: failed to parse namespace selector: %w", cluster, err)
+		}
+
+		policy.selector = selector
+	}
+
+	return checker, nil
+}
+
+// policy returns the policy for the cluster, or nil if there is none.
+func (c *Checker) policy(cluster string) *Policy {
+	if c == nil {
+		return nil
+	}
+
+	if policy, ok := c.policies[cluster]; ok {
+		return policy
+	}
+
+	return c.policies[Wildcard]
+}
+
+// Target checks the namespace name and kind before any objects are read.
+func (c *Checker) Target(cluster, namespace, group, kind string) error {
+	policy := c.policy(cluster)
+	if policy == nil {
+		return nil
+	}
+
+	if !MatchNamespace(policy.Namespaces, namespace) {
+		return fmt.Errorf("%w: namespace %s is not allowed", ErrDenied, namespace)
+	}
+
+	return c.Kind(cluster, group, kind)
+}
+
+// Kind checks the kind of a target or owner.
+func (c *Checker) Kind(cluster, group, kind string) error {
+	policy := c.policy(cluster)
+	if policy == nil || MatchKind(policy.Kinds, group, kind) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: kind %s/%s is not allowed", ErrDenied, group, kind)
+}
+
+// MatchKind returns true if the kind matches any of the "group/Kind" globs, or there are no patterns.
+func MatchKind(patterns []string, group, kind string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		groupPattern, kindPattern := "", pattern
+		if i := strings.LastIndex(pattern, "/"); i >= 0 {
+			groupPattern, kindPattern = pattern[:i], pattern[i+1:]
+		}
+
+		groupOK, _ := path.Match(groupPattern, group)
+		kindOK, _ := path.Match(kindPattern, kind)
+
+		if groupOK && kindOK {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Namespace checks the namespace labels and, if required, that the alarm belongs to the namespace owner.
+func (c *Checker) Namespace(cluster string, namespace metav1.Object, alarmARN, alarmName string) error {
+	policy := c.policy(cluster)
+	if policy == nil {
+		return nil
+	}
+
+	if !policy.selector.Matches(labels.Set(namespace.GetLabels())) {
+		return fmt.Errorf("%w: namespace %s does not match selector %s", ErrDenied, namespace.GetName(), policy.NamespaceSelector)
+	}
+
+	if !policy.RequireOwner {
+		return nil
+	}
+
+	var (
+		annotations           = namespace.GetAnnotations()
+		accounts, hasAccounts = annotations[annotation.KeyAlarmAccounts]
+		prefix, hasPrefix     = annotations[annotation.KeyAlarmPrefix]
+	)
+
+	if !hasAccounts && !hasPrefix {
+		return fmt.Errorf("%w: namespace %s does not declare an owner", ErrDenied, namespace.GetName())
+	}
+
+	if hasAccounts {
+		parsed, err := arn.Parse(alarmARN)
+		if err != nil || !contains(accounts, parsed.AccountID) {
+			return fmt.Errorf("%w: alarm account is not an owner of namespace %s", ErrDenied, namespace.GetName())
+		}
+	}
+
+	if hasPrefix && !strings.HasPrefix(alarmName, prefix) {
+		return fmt.Errorf("%w: alarm name does not have the prefix %q required by namespace %s", ErrDenied, prefix, namespace.GetName())
+	}
+
+	return nil
+}
+
+// MatchNamespace returns true if the namespace matches any of the globs, or there are no patterns.
+func MatchNamespace(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contains returns true if the comma separated list contains the value.
+func contains(list, value string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == value {
+			return true
+		}
+	}
+
+	return false
+}
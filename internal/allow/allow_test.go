@@ -0,0 +1,74 @@
+package allow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+const alarmARN = "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:project-drupal-5xx"
+
+func TestTarget(t *testing.T) {
+	checker, err := New(`{
+		"prod": {"namespaces": ["skpr-*"], "kinds": ["workflow.skpr.io/Environment", "apps/*", "Pod"]},
+		"*": {"namespaces": ["dev"]}
+	}`)
+	assert.NoError(t, err)
+
+	assert.NoError(t, checker.Target("prod", "skpr-test", "workflow.skpr.io", "Environment"))
+	assert.NoError(t, checker.Target("prod", "skpr-test", "apps", "Deployment"))
+	assert.NoError(t, checker.Target("prod", "skpr-test", "", "Pod"))
+	assert.ErrorIs(t, checker.Target("prod", "kube-system", "", "Pod"), ErrDenied)
+	assert.ErrorIs(t, checker.Target("prod", "skpr-test", "", "Secret"), ErrDenied)
+	assert.ErrorIs(t, checker.Target("prod", "skpr-test", "batch", "Job"), ErrDenied)
+
+	assert.NoError(t, checker.Target("other", "dev", "", "Secret"))
+	assert.ErrorIs(t, checker.Target("other", "prod", "", "Pod"), ErrDenied)
+}
+
+func TestTargetNoPolicy(t *testing.T) {
+	checker, err := New("")
+	assert.NoError(t, err)
+	assert.NoError(t, checker.Target("prod", "kube-system", "", "Secret"))
+
+	var nilChecker *Checker
+	assert.NoError(t, nilChecker.Target("prod", "kube-system", "", "Secret"))
+}
+
+func TestNamespace(t *testing.T) {
+	checker, err := New(`{"*": {"namespaceSelector": "skpr.io/managed=true", "requireOwner": true}}`)
+	assert.NoError(t, err)
+
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test",
+			Labels: map[string]string{"skpr.io/managed": "true"},
+		},
+	}
+
+	assert.ErrorIs(t, checker.Namespace("prod", namespace, alarmARN, "project-drupal-5xx"), ErrDenied, "namespace without an owner")
+
+	namespace.Annotations = map[string]string{
+		annotation.KeyAlarmAccounts: "111111111111, 123456789012",
+		annotation.KeyAlarmPrefix:   "project-",
+	}
+
+	assert.NoError(t, checker.Namespace("prod", namespace, alarmARN, "project-drupal-5xx"))
+	assert.ErrorIs(t, checker.Namespace("prod", namespace, alarmARN, "other-drupal-5xx"), ErrDenied)
+	assert.ErrorIs(t, checker.Namespace("prod", namespace, "arn:aws:cloudwatch:ap-southeast-2:999999999999:alarm:x", "project-x"), ErrDenied)
+
+	namespace.Labels = nil
+	assert.ErrorIs(t, checker.Namespace("prod", namespace, alarmARN, "project-drupal-5xx"), ErrDenied)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(`{"*": {"namespaceSelector": "!!"}}`)
+	assert.Error(t, err)
+
+	_, err = New(`[`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+const (
+	// DefaultConcurrency is the default number of records prepared, and clusters processed, at the same time.
+	DefaultConcurrency = 4
+	// DefaultGroupConcurrency is the default number of alarms processed at the same time for each cluster.
+	DefaultGroupConcurrency = 4
+
+	// NotificationType is the type of an SNS message delivered to SQS without raw message delivery.
+	NotificationType = "Notification"
+
+	// SourceSQS is the event source of SQS records, which support reporting partial batch failures.
+	SourceSQS = "aws:sqs"
+)
+
+// ItemFailure identifies a record which failed so SQS only retries that record.
+type ItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// SQS returns true if all the records are from SQS.
+func SQS(records []Record) bool {
+	for _, record := range records {
+		if record.EventSource != SourceSQS {
+			return false
+		}
+	}
+
+	return len(records) > 0
+}
+
+// Record is a single message from an SQS or SNS batch.
+type Record struct {
+	MessageID   string      `json:"messageId"`
+	EventSource string      `json:"eventSource"`
+	Body        string      `json:"body"`
+	SNS         *SNSMessage `json:"Sns,omitempty"`
+}
+
+// SNSMessage is the SNS notification carried by an SNS record, or by an SQS body when raw message delivery is disabled.
+type SNSMessage struct {
+	MessageID string `json:"MessageId"`
+	Type      string `json:"Type"`
+	Message   string `json:"Message"`
+}
+
+// ID of the record, used to report partial batch failures.
+func (r Record) ID() string {
+	if r.SNS != nil && r.MessageID == "" {
+		return r.SNS.MessageID
+	}
+
+	return r.MessageID
+}
+
+// Payload carried by the record, unwrapping SNS notifications.
+func (r Record) Payload() []byte {
+	if r.SNS != nil {
+		return []byte(r.SNS.Message)
+	}
+
+	var notification SNSMessage
+
+	if err := json.Unmarshal([]byte(r.Body), &notification); err == nil && notification.Type == NotificationType {
+		return []byte(notification.Message)
+	}
+
+	return []byte(r.Body)
+}
+
+// Options for processing tasks.
+type Options struct {
+	// Concurrency is the number of groups processed at the same time.
+	Concurrency int
+	// GroupConcurrency is the number of keys processed at the same time within a group.
+	GroupConcurrency int
+}
+
+// Task is a unit of work for a record.
+type Task struct {
+	// Record is the index of the record the task belongs to.
+	Record int
+	// Group is shared by tasks which use the same connection eg. the cluster.
+	Group string
+	// Key is shared by tasks which must run in order eg. the alarm ARN.
+	Key string
+	// Run the task.
+	Run func(ctx context.Context) error
+}
+
+// Each calls fn for each index from 0 to n, with at most limit calls at the same time.
+func Each(n, limit int, fn func(i int)) {
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, max(limit, 1))
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// Run the tasks grouped by their group so each group can share a connection. Groups are processed concurrently, and
+// tasks within a group are processed concurrently by key. Tasks with the same key run in the order they were given.
+// The error for each task is returned at the same index.
+func Run(ctx context.Context, tasks []Task, opts Options) []error {
+	var (
+		errs   = make([]error, len(tasks))
+		order  []string
+		groups = make(map[string][]int)
+	)
+
+	for i, task := range tasks {
+		if _, ok := groups[task.Group]; !ok {
+			order = append(order, task.Group)
+		}
+
+		groups[task.Group] = append(groups[task.Group], i)
+	}
+
+	Each(len(order), opts.Concurrency, func(g int) {
+		var (
+			keyOrder []string
+			keys     = make(map[string][]int)
+		)
+
+		for _, i := range groups[order[g]] {
+			if _, ok := keys[tasks[i].Key]; !ok {
+				keyOrder = append(keyOrder, tasks[i].Key)
+			}
+
+			keys[tasks[i].Key] = append(keys[tasks[i].Key], i)
+		}
+
+		Each(len(keyOrder), opts.GroupConcurrency, func(k int) {
+			for _, i := range keys[keyOrder[k]] {
+				errs[i] = tasks[i].Run(ctx)
+			}
+		})
+	})
+
+	return errs
+}
@@ -0,0 +1,95 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordPayload(t *testing.T) {
+	var records struct {
+		Records []Record `json:"Records"`
+	}
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"Records": [
+		{"messageId": "sqs-1", "eventSource": "aws:sqs", "body": "{\"alarmArn\": \"a\"}"},
+		{"messageId": "sqs-2", "eventSource": "aws:sqs", "body": "{\"Type\": \"Notification\", \"MessageId\": \"sns-1\", \"Message\": \"{\\\"alarmArn\\\": \\\"b\\\"}\"}"},
+		{"EventSource": "aws:sns", "Sns": {"MessageId": "sns-2", "Type": "Notification", "Message": "{\"alarmArn\": \"c\"}"}}
+	]}`), &records))
+
+	assert.Len(t, records.Records, 3)
+
+	assert.Equal(t, "sqs-1", records.Records[0].ID())
+	assert.JSONEq(t, `{"alarmArn": "a"}`, string(records.Records[0].Payload()))
+
+	assert.Equal(t, "sqs-2", records.Records[1].ID())
+	assert.JSONEq(t, `{"alarmArn": "b"}`, string(records.Records[1].Payload()))
+
+	assert.Equal(t, "sns-2", records.Records[2].ID())
+	assert.JSONEq(t, `{"alarmArn": "c"}`, string(records.Records[2].Payload()))
+
+	assert.True(t, SQS(records.Records[:2]))
+	assert.False(t, SQS(records.Records))
+	assert.False(t, SQS(nil))
+}
+
+func TestRun(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order = make(map[string][]int)
+		tasks []Task
+	)
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("alarm-%d", i%3)
+
+		tasks = append(tasks, Task{
+			Record: i,
+			Group:  fmt.Sprintf("cluster-%d", i%2),
+			Key:    key,
+			Run: func(ctx context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				order[key] = append(order[key], i)
+
+				if i == 5 {
+					return fmt.Errorf("failed")
+				}
+
+				return nil
+			},
+		})
+	}
+
+	errs := Run(context.Background(), tasks, Options{Concurrency: 2, GroupConcurrency: 2})
+	assert.Len(t, errs, 20)
+
+	for i, err := range errs {
+		if i == 5 {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	// Tasks for the same alarm in the same cluster run in the order they were given.
+	for key, indexes := range order {
+		for _, group := range []int{0, 1} {
+			var last = -1
+
+			for _, i := range indexes {
+				if i%2 != group {
+					continue
+				}
+
+				assert.Greater(t, i, last, key)
+				last = i
+			}
+		}
+	}
+}
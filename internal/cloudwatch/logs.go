@@ -0,0 +1,144 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MessageTypeData is the message type of a payload containing log events.
+	MessageTypeData = "DATA_MESSAGE"
+	// MessageTypeControl is the message type CloudWatch Logs uses to check the destination is reachable.
+	MessageTypeControl = "CONTROL_MESSAGE"
+
+	// MessageLimit is the maximum length of an aggregated message, matching the Kubernetes event note limit.
+	MessageLimit = 1024
+)
+
+// LogsEvent is the payload sent by a CloudWatch Logs subscription filter.
+type LogsEvent struct {
+	AWSLogs LogsEventData `json:"awslogs"`
+}
+
+// LogsEventData contains the gzipped and base64 encoded log data.
+type LogsEventData struct {
+	Data string `json:"data"`
+}
+
+// LogsData is the decoded subscription filter payload.
+type LogsData struct {
+	Owner               string     `json:"owner"`
+	LogGroup            string     `json:"logGroup"`
+	LogStream           string     `json:"logStream"`
+	SubscriptionFilters []string   `json:"subscriptionFilters"`
+	MessageType         string     `json:"messageType"`
+	LogEvents           []LogEvent `json:"logEvents"`
+}
+
+// LogEvent is a single log line.
+type LogEvent struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// Decode the gzipped and base64 encoded log data.
+func (e LogsEvent) Decode() (*LogsData, error) {
+	compressed, err := base64.StdEncoding.DecodeString(e.AWSLogs.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode logs data: %w", err)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress logs data: %w", err)
+	}
+
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress logs data: %w", err)
+	}
+
+	var data LogsData
+
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal logs data: %w", err)
+	}
+
+	return &data, nil
+}
+
+// LogGroupARN returns the ARN of the log group, which is used to look up its tags.
+func (d *LogsData) LogGroupARN(region string) string {
+	return fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s", region, d.Owner, d.LogGroup)
+}
+
+// Filter returns the name of the subscription filter, or the log group if there is none.
+func (d *LogsData) Filter() string {
+	if len(d.SubscriptionFilters) > 0 {
+		return d.SubscriptionFilters[0]
+	}
+
+	return d.LogGroup
+}
+
+// Timestamps of the first and last log events.
+func (d *LogsData) Timestamps() (time.Time, time.Time) {
+	var first, last time.Time
+
+	for _, event := range d.LogEvents {
+		t := time.UnixMilli(event.Timestamp).UTC()
+
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	return first, last
+}
+
+// Summary aggregates the batch into a single message with a sample of log lines, truncated to the limit.
+func (d *LogsData) Summary(limit int) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d log events matched %s in %s", len(d.LogEvents), d.Filter(), d.LogGroup)
+
+	for _, event := range d.LogEvents {
+		line := "\n" + strings.TrimSpace(event.Message)
+
+		if b.Len()+len(line) > limit {
+			break
+		}
+
+		b.WriteString(line)
+	}
+
+	return Truncate(b.String(), limit)
+}
+
+// Truncate the message to at most limit bytes without splitting a UTF-8 character.
+func Truncate(message string, limit int) string {
+	if len(message) <= limit {
+		return message
+	}
+
+	end := limit
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+
+	return message[:end]
+}
@@ -0,0 +1,94 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeLogs(t *testing.T, data string) LogsEvent {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	return LogsEvent{
+		AWSLogs: LogsEventData{
+			Data: base64.StdEncoding.EncodeToString(buf.Bytes()),
+		},
+	}
+}
+
+func TestLogsEventDecode(t *testing.T) {
+	event := encodeLogs(t, `{
+		"owner": "123456789012",
+		"logGroup": "/skpr/project/prod/php",
+		"logStream": "drupal-1",
+		"subscriptionFilters": ["php-fatal"],
+		"messageType": "DATA_MESSAGE",
+		"logEvents": [
+			{"id": "1", "timestamp": 1704110400000, "message": "PHP Fatal error: first\n"},
+			{"id": "2", "timestamp": 1704110460000, "message": "PHP Fatal error: second\n"}
+		]
+	}`)
+
+	data, err := event.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeData, data.MessageType)
+	assert.Equal(t, "php-fatal", data.Filter())
+	assert.Equal(t, "arn:aws:logs:ap-southeast-2:123456789012:log-group:/skpr/project/prod/php", data.LogGroupARN("ap-southeast-2"))
+
+	first, last := data.Timestamps()
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), first)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 1, 0, 0, time.UTC), last)
+
+	assert.Equal(t, "2 log events matched php-fatal in /skpr/project/prod/php\nPHP Fatal error: first\nPHP Fatal error: second", data.Summary(MessageLimit))
+
+	_, err = LogsEvent{AWSLogs: LogsEventData{Data: "not base64"}}.Decode()
+	assert.Error(t, err)
+}
+
+func TestLogsDataSummaryLimit(t *testing.T) {
+	data := &LogsData{LogGroup: "test"}
+
+	for i := 0; i < 100; i++ {
+		data.LogEvents = append(data.LogEvents, LogEvent{Message: strings.Repeat("x", 100)})
+	}
+
+	summary := data.Summary(MessageLimit)
+	assert.LessOrEqual(t, len(summary), MessageLimit)
+	assert.True(t, strings.HasPrefix(summary, "100 log events matched test in test\n"))
+}
+
+func TestLogsDataSummaryMultibyte(t *testing.T) {
+	// The summary line alone is over the limit, so it is truncated.
+	data := &LogsData{LogGroup: strings.Repeat("日本", 10)}
+
+	for i := 0; i < 100; i++ {
+		data.LogEvents = append(data.LogEvents, LogEvent{Message: strings.Repeat("é", 7)})
+	}
+
+	for limit := 40; limit < 60; limit++ {
+		summary := data.Summary(limit)
+		assert.LessOrEqual(t, len(summary), limit)
+		assert.True(t, utf8.ValidString(summary), summary)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 10))
+	assert.Equal(t, "ab", Truncate("abc", 2))
+
+	// "é" is two bytes, so it is dropped rather than split.
+	assert.Equal(t, "a", Truncate("aé", 2))
+	assert.Equal(t, "aé", Truncate("aé", 3))
+	assert.Equal(t, "", Truncate("日本", 2))
+}
@@ -0,0 +1,97 @@
+package cloudwatch
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Notification is the message published to SNS when a CloudWatch Alarm with an SNS action changes state.
+type Notification struct {
+	AlarmName        string              `json:"AlarmName"`
+	AlarmDescription string              `json:"AlarmDescription"`
+	AlarmARN         string              `json:"AlarmArn"`
+	NewStateValue    string              `json:"NewStateValue"`
+	NewStateReason   string              `json:"NewStateReason"`
+	StateChangeTime  string              `json:"StateChangeTime"`
+	OldStateValue    string              `json:"OldStateValue"`
+	Trigger          NotificationTrigger `json:"Trigger"`
+}
+
+// NotificationTrigger is the metric which the alarm evaluates.
+type NotificationTrigger struct {
+	MetricName string                  `json:"MetricName"`
+	Namespace  string                  `json:"Namespace"`
+	Statistic  string                  `json:"Statistic"`
+	Period     int                     `json:"Period"`
+	Dimensions []NotificationDimension `json:"Dimensions"`
+}
+
+// NotificationDimension of the metric which the alarm evaluates.
+type NotificationDimension struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ParseNotification returns the alarm event for an SNS alarm notification. Nil is returned if the message is not an
+// alarm notification.
+func ParseNotification(data []byte) (*Event, error) {
+	var notification Notification
+
+	if err := json.Unmarshal(data, &notification); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notification: %w", err)
+	}
+
+	if notification.NewStateValue == "" || notification.AlarmARN == "" {
+		return nil, nil
+	}
+
+	return notification.Event(), nil
+}
+
+// Event converts the notification into the alarm event delivered to Lambda alarm actions. The notification does not
+// include the reason data or when the alarm entered its previous state, so they are left empty.
+func (n Notification) Event() *Event {
+	event := &Event{
+		AlarmARN: n.AlarmARN,
+		AlarmData: AlarmData{
+			AlarmName: n.AlarmName,
+			State: AlarmDataState{
+				Value:     n.NewStateValue,
+				Reason:    n.NewStateReason,
+				Timestamp: n.StateChangeTime,
+			},
+			PreviousState: AlarmDataState{
+				Value: n.OldStateValue,
+			},
+			Configuration: AlarmDataConfiguration{
+				Description: n.AlarmDescription,
+			},
+		},
+	}
+
+	if n.Trigger.MetricName != "" {
+		dimensions := make(map[string]string, len(n.Trigger.Dimensions))
+
+		for _, dimension := range n.Trigger.Dimensions {
+			dimensions[dimension.Name] = dimension.Value
+		}
+
+		event.AlarmData.Configuration.Metrics = []AlarmDataConfigurationMetric{
+			{
+				ID: "m1",
+				MetricStat: AlarmDataConfigurationMetricStat{
+					Metric: AlarmDataConfigurationMetricStatMetric{
+						Namespace:  n.Trigger.Namespace,
+						Name:       n.Trigger.MetricName,
+						Dimensions: dimensions,
+					},
+					Period: n.Trigger.Period,
+					Stat:   n.Trigger.Statistic,
+				},
+				ReturnData: true,
+			},
+		}
+	}
+
+	return event
+}
@@ -0,0 +1,48 @@
+package cloudwatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNotification(t *testing.T) {
+	event, err := ParseNotification([]byte(`{
+		"AlarmName": "drupal-5xx",
+		"AlarmDescription": "High error rate",
+		"AWSAccountId": "123456789012",
+		"NewStateValue": "ALARM",
+		"NewStateReason": "Threshold Crossed: 1 datapoint [10.0] was greater than the threshold (5.0).",
+		"StateChangeTime": "2024-01-01T12:00:00.000+0000",
+		"Region": "Asia Pacific (Sydney)",
+		"AlarmArn": "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:drupal-5xx",
+		"OldStateValue": "OK",
+		"Trigger": {
+			"MetricName": "HTTPCode_Target_5XX_Count",
+			"Namespace": "AWS/ApplicationELB",
+			"Statistic": "SUM",
+			"Period": 60,
+			"Dimensions": [{"name": "LoadBalancer", "value": "app/drupal/123"}]
+		}
+	}`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:drupal-5xx", event.AlarmARN)
+	assert.Equal(t, "drupal-5xx", event.AlarmData.AlarmName)
+	assert.Equal(t, "ALARM", event.AlarmData.State.Value)
+	assert.Equal(t, "OK", event.AlarmData.PreviousState.Value)
+	assert.Equal(t, "High error rate", event.AlarmData.Configuration.Description)
+	assert.Equal(t, "HTTPCode_Target_5XX_Count", event.AlarmData.Configuration.Metric())
+	assert.Equal(t, map[string]string{"LoadBalancer": "app/drupal/123"}, event.AlarmData.Configuration.Metrics[0].MetricStat.Metric.Dimensions)
+
+	_, err = ParseTimestamp(event.AlarmData.State.Timestamp)
+	assert.NoError(t, err)
+
+	// The alarm action payload is not a notification.
+	event, err = ParseNotification([]byte(`{"alarmArn": "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:drupal-5xx", "alarmData": {}}`))
+	assert.NoError(t, err)
+	assert.Nil(t, event)
+
+	_, err = ParseNotification([]byte(`not json`))
+	assert.Error(t, err)
+}
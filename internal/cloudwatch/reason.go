@@ -0,0 +1,172 @@
+package cloudwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+// operators found in the state reason, ordered so the longest phrases are matched first.
+var operators = []struct {
+	phrase   string
+	operator string
+}{
+	{"greater than or equal to", ">="},
+	{"less than or equal to", "<="},
+	{"greater than", ">"},
+	{"less than", "<"},
+}
+
+// ParseReasonData from the JSON string in the alarm state. Nil is returned if there is no reason data.
+func ParseReasonData(value string) (*ReasonData, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var data ReasonData
+
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reason data: %w", err)
+	}
+
+	return &data, nil
+}
+
+// Operator returns the comparison operator described by the state reason eg. ">", or empty if there is none.
+func Operator(reason string) string {
+	for _, o := range operators {
+		if strings.Contains(reason, "was "+o.phrase) || strings.Contains(reason, "were "+o.phrase) {
+			return o.operator
+		}
+	}
+
+	return ""
+}
+
+// Latest returns the most recent datapoint value.
+func (d *ReasonData) Latest() (float64, bool) {
+	if len(d.RecentDatapoints) > 0 {
+		return d.RecentDatapoints[len(d.RecentDatapoints)-1], true
+	}
+
+	// Evaluated datapoints are ordered newest first.
+	for _, datapoint := range d.EvaluatedDatapoints {
+		if datapoint.Value != nil {
+			return *datapoint.Value, true
+		}
+	}
+
+	return 0, false
+}
+
+// Breaching returns the number of evaluated datapoints which breached the threshold using the operator.
+func (d *ReasonData) Breaching(operator string) int {
+	var count int
+
+	for _, datapoint := range d.EvaluatedDatapoints {
+		if datapoint.Value == nil {
+			continue
+		}
+
+		v := *datapoint.Value
+
+		switch operator {
+		case ">=":
+			if v >= d.Threshold {
+				count++
+			}
+		case "<=":
+			if v <= d.Threshold {
+				count++
+			}
+		case ">":
+			if v > d.Threshold {
+				count++
+			}
+		case "<":
+			if v < d.Threshold {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+// Summary is a compact description of the datapoints eg. "HTTPCode_Target_5XX_Count Sum 42 > 10 for 3 of 3 periods".
+func (d *ReasonData) Summary(metric, reason string) string {
+	var parts []string
+
+	if metric != "" {
+		parts = append(parts, metric)
+	}
+
+	if d.Statistic != "" {
+		parts = append(parts, d.Statistic)
+	}
+
+	if latest, ok := d.Latest(); ok {
+		parts = append(parts, FormatValue(latest))
+	}
+
+	operator := Operator(reason)
+	if operator == "" {
+		return fmt.Sprintf("%s (threshold %s)", strings.Join(parts, " "), FormatValue(d.Threshold))
+	}
+
+	parts = append(parts, operator, FormatValue(d.Threshold))
+
+	if len(d.EvaluatedDatapoints) > 0 {
+		parts = append(parts, fmt.Sprintf("for %d of %d periods", d.Breaching(operator), len(d.EvaluatedDatapoints)))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Annotations containing the raw values for tools.
+func (d *ReasonData) Annotations() (map[string]string, error) {
+	datapoints, err := json.Marshal(d.EvaluatedDatapoints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal datapoints: %w", err)
+	}
+
+	annotations := map[string]string{
+		annotation.KeyThreshold:  FormatValue(d.Threshold),
+		annotation.KeyDatapoints: string(datapoints),
+	}
+
+	if d.Statistic != "" {
+		annotations[annotation.KeyStatistic] = d.Statistic
+	}
+
+	if d.Period > 0 {
+		annotations[annotation.KeyPeriod] = strconv.Itoa(d.Period)
+	}
+
+	return annotations, nil
+}
+
+// FormatValue without trailing zeros eg. "42" or "0.5".
+func FormatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Metric returns the name of the metric the alarm evaluates, preferring the metric which returns data.
+func (c AlarmDataConfiguration) Metric() string {
+	for _, metric := range c.Metrics {
+		if metric.ReturnData && metric.MetricStat.Metric.Name != "" {
+			return metric.MetricStat.Metric.Name
+		}
+	}
+
+	for _, metric := range c.Metrics {
+		if metric.MetricStat.Metric.Name != "" {
+			return metric.MetricStat.Metric.Name
+		}
+	}
+
+	return ""
+}
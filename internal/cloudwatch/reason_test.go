@@ -0,0 +1,59 @@
+package cloudwatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+const testReasonData = `{
+	"version": "1.0",
+	"queryDate": "2024-01-01T10:30:00.123+0000",
+	"startDate": "2024-01-01T10:27:00.000+0000",
+	"statistic": "Sum",
+	"period": 60,
+	"recentDatapoints": [12.0, 30.0, 42.0],
+	"threshold": 10.0,
+	"evaluatedDatapoints": [
+		{"timestamp": "2024-01-01T10:29:00.000+0000", "sampleCount": 5.0, "value": 42.0},
+		{"timestamp": "2024-01-01T10:28:00.000+0000", "sampleCount": 5.0, "value": 30.0},
+		{"timestamp": "2024-01-01T10:27:00.000+0000", "sampleCount": 5.0, "value": 12.0}
+	]
+}`
+
+func TestReasonData(t *testing.T) {
+	data, err := ParseReasonData(testReasonData)
+	assert.NoError(t, err)
+	assert.Equal(t, "Sum", data.Statistic)
+	assert.Equal(t, 10.0, data.Threshold)
+	assert.Len(t, data.EvaluatedDatapoints, 3)
+
+	reason := "Threshold Crossed: 3 out of the last 3 datapoints [42.0 (01/01/24 10:29:00), 30.0 (01/01/24 10:28:00)] were greater than the threshold (10.0)."
+	assert.Equal(t, ">", Operator(reason))
+	assert.Equal(t, "HTTPCode_Target_5XX_Count Sum 42 > 10 for 3 of 3 periods", data.Summary("HTTPCode_Target_5XX_Count", reason))
+	assert.Equal(t, "Sum 42 (threshold 10)", data.Summary("", "Unchecked: Initial alarm creation"))
+	assert.Equal(t, 0, data.Breaching("<="))
+
+	annotations, err := data.Annotations()
+	assert.NoError(t, err)
+	assert.Equal(t, "10", annotations[annotation.KeyThreshold])
+	assert.Equal(t, "Sum", annotations[annotation.KeyStatistic])
+	assert.Equal(t, "60", annotations[annotation.KeyPeriod])
+	assert.Contains(t, annotations[annotation.KeyDatapoints], `"value":42`)
+
+	data, err = ParseReasonData("")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	_, err = ParseReasonData("{")
+	assert.Error(t, err)
+}
+
+func TestOperator(t *testing.T) {
+	assert.Equal(t, ">=", Operator("1 datapoint [5.0] was greater than or equal to the threshold (5.0)."))
+	assert.Equal(t, "<=", Operator("1 datapoint [5.0] was less than or equal to the threshold (5.0)."))
+	assert.Equal(t, "<", Operator("1 datapoint [1.0] was less than the threshold (5.0)."))
+	assert.Equal(t, "", Operator("1 datapoint [1.0] was not greater than the threshold (5.0)."))
+}
@@ -16,3 +16,18 @@ func GetValueFromTag(tags []types.Tag, key string) (string, error) {
 
 	return "", fmt.Errorf("tag not found")
 }
+
+// TagsToMap converts a list of CloudWatch tags into a map keyed by tag key.
+func TagsToMap(tags []types.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+
+	for _, tag := range tags {
+		if tag.Key == nil || tag.Value == nil {
+			continue
+		}
+
+		m[*tag.Key] = *tag.Value
+	}
+
+	return m
+}
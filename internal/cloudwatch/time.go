@@ -0,0 +1,24 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampLayout is the format of timestamps in CloudWatch Alarm events eg. 2024-01-01T00:00:00.000+0000.
+const TimestampLayout = "2006-01-02T15:04:05.000-0700"
+
+// ParseTimestamp parses a timestamp from a CloudWatch Alarm event. An empty value returns the zero time.
+func ParseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{TimestampLayout, time.RFC3339Nano} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown timestamp format: %s", value)
+}
@@ -0,0 +1,25 @@
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	ts, err := ParseTimestamp("2024-01-01T10:30:00.123+0000")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 30, 0, 123000000, time.UTC), ts.UTC())
+
+	ts, err = ParseTimestamp("2024-01-01T10:30:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC), ts.UTC())
+
+	ts, err = ParseTimestamp("")
+	assert.NoError(t, err)
+	assert.True(t, ts.IsZero())
+
+	_, err = ParseTimestamp("yesterday")
+	assert.Error(t, err)
+}
@@ -10,15 +10,61 @@ type Event struct {
 type AlarmData struct {
 	AlarmName     string                 `json:"alarmName"`
 	State         AlarmDataState         `json:"state"`
+	PreviousState AlarmDataState         `json:"previousState"`
 	Configuration AlarmDataConfiguration `json:"configuration"`
 }
 
 // AlarmDataState used to check the previous and current state of the CloudWatch Alarm.
 type AlarmDataState struct {
-	Reason string `json:"reason"`
+	Value      string `json:"value"`
+	Reason     string `json:"reason"`
+	ReasonData string `json:"reasonData"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// ReasonData is the JSON encoded detail of the datapoints which caused the CloudWatch Alarm to change state.
+type ReasonData struct {
+	Version             string               `json:"version"`
+	QueryDate           string               `json:"queryDate"`
+	StartDate           string               `json:"startDate"`
+	Statistic           string               `json:"statistic"`
+	Unit                string               `json:"unit"`
+	Period              int                  `json:"period"`
+	RecentDatapoints    []float64            `json:"recentDatapoints"`
+	Threshold           float64              `json:"threshold"`
+	EvaluatedDatapoints []EvaluatedDatapoint `json:"evaluatedDatapoints"`
+}
+
+// EvaluatedDatapoint used to review a datapoint the CloudWatch Alarm evaluated. Value is missing when there was no data.
+type EvaluatedDatapoint struct {
+	Timestamp   string   `json:"timestamp"`
+	SampleCount float64  `json:"sampleCount"`
+	Value       *float64 `json:"value"`
 }
 
 // AlarmDataConfiguration used to review the configuration of the CloudWatch Alarm.
 type AlarmDataConfiguration struct {
-	Description string `json:"description"`
+	Description string                         `json:"description"`
+	Metrics     []AlarmDataConfigurationMetric `json:"metrics"`
+}
+
+// AlarmDataConfigurationMetric used to review the metrics which the CloudWatch Alarm is evaluating.
+type AlarmDataConfigurationMetric struct {
+	ID         string                           `json:"id"`
+	MetricStat AlarmDataConfigurationMetricStat `json:"metricStat"`
+	ReturnData bool                             `json:"returnData"`
+}
+
+// AlarmDataConfigurationMetricStat used to review the metric and statistic being evaluated.
+type AlarmDataConfigurationMetricStat struct {
+	Metric AlarmDataConfigurationMetricStatMetric `json:"metric"`
+	Period int                                    `json:"period"`
+	Stat   string                                 `json:"stat"`
+}
+
+// AlarmDataConfigurationMetricStatMetric used to identify the metric being evaluated.
+type AlarmDataConfigurationMetricStatMetric struct {
+	Namespace  string            `json:"namespace"`
+	Name       string            `json:"name"`
+	Dimensions map[string]string `json:"dimensions"`
 }
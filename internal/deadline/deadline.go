@@ -0,0 +1,61 @@
+package deadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultMargin is how long before the Lambda deadline the invocation gives up.
+	DefaultMargin = 2 * time.Second
+	// DefaultStageTimeout is the maximum time a single stage can take.
+	DefaultStageTimeout = 10 * time.Second
+)
+
+// ErrTransient is returned when the invocation ran out of time and can safely be retried.
+var ErrTransient = errors.New("transient error: ran out of time before the lambda deadline")
+
+// WithMargin returns a context which expires the margin before the parent's deadline.
+// If the parent has no deadline it is returned unchanged.
+func WithMargin(ctx context.Context, margin time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, deadline.Add(-margin))
+}
+
+// Stage returns a context which expires after the timeout, or the parent's deadline if it is sooner.
+func Stage(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Remaining returns the time left before the context's deadline, or zero if it has no deadline.
+func Remaining(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+
+	return time.Until(deadline)
+}
+
+// Wrap an error as transient if the context ran out of time.
+func Wrap(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTransient, err)
+	}
+
+	return err
+}
@@ -0,0 +1,54 @@
+package deadline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMargin(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	ctx, cancel := WithMargin(parent, 10*time.Second)
+	defer cancel()
+
+	parentDeadline, _ := parent.Deadline()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, parentDeadline.Add(-10*time.Second), deadline)
+
+	ctx, cancel = WithMargin(context.TODO(), 10*time.Second)
+	defer cancel()
+
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
+
+func TestStage(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	ctx, cancel := Stage(parent, time.Minute)
+	defer cancel()
+
+	// The parent deadline is sooner than the stage timeout.
+	assert.LessOrEqual(t, Remaining(ctx), time.Second)
+}
+
+func TestWrap(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), -time.Second)
+	defer cancel()
+
+	err := Wrap(ctx, fmt.Errorf("failed to create event: %w", ctx.Err()))
+	assert.ErrorIs(t, err, ErrTransient)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = Wrap(context.TODO(), fmt.Errorf("failed"))
+	assert.NotErrorIs(t, err, ErrTransient)
+
+	assert.NoError(t, Wrap(ctx, nil))
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"k8s.io/client-go/rest"
 
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/metrics"
 	skprsts "github.com/skpr/lambda-eks-event-cloudwatch/internal/sts"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/tracing"
 )
 
 // BuildKubeconfig for a given EKS cluster.
@@ -21,14 +23,24 @@ func BuildKubeconfig(ctx context.Context, eksClient *eks.Client, cluster string)
 		return nil, fmt.Errorf("failed to get aws config: %w", err)
 	}
 
+	tracing.InstrumentAWS(&cfg)
+
+	recorder := metrics.FromContext(ctx)
+
+	stop := recorder.Stage(metrics.StageDescribeCluster)
+	spanCtx, span := tracing.Start(ctx, metrics.StageDescribeCluster)
+
 	// Query EKS for the CA etc.
-	resp, err := eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{
+	resp, err := eksClient.DescribeCluster(spanCtx, &eks.DescribeClusterInput{
 		Name: aws.String(cluster),
 	})
+	tracing.End(span, err)
 	if err != nil {
 		return nil, err
 	}
 
+	stop()
+
 	ca, err := base64.StdEncoding.DecodeString(*resp.Cluster.CertificateAuthority.Data)
 	if err != nil {
 		return nil, err
@@ -41,11 +53,17 @@ func BuildKubeconfig(ctx context.Context, eksClient *eks.Client, cluster string)
 
 	gen := skprsts.NewTokenGenerator(stsPresignClient)
 
-	token, err := gen.GenerateToken(ctx, cluster)
+	stop = recorder.Stage(metrics.StageToken)
+	spanCtx, span = tracing.Start(ctx, metrics.StageToken)
+
+	token, err := gen.GenerateToken(spanCtx, cluster)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sts token: %w", err)
 	}
 
+	stop()
+
 	return &rest.Config{
 		UserAgent:   "Skpr Lambda EKS Event CloudWatch",
 		BearerToken: token,
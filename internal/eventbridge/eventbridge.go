@@ -0,0 +1,203 @@
+package eventbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/cloudwatch"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/severity"
+)
+
+const (
+	// SourceRDS is the source of RDS DB instance, cluster and snapshot events.
+	SourceRDS = "aws.rds"
+	// SourceElastiCache is the source of ElastiCache events.
+	SourceElastiCache = "aws.elasticache"
+	// SourceHealth is the source of AWS Health events.
+	SourceHealth = "aws.health"
+
+	// HealthStatusClosed is the status of an AWS Health event which has been resolved.
+	HealthStatusClosed = "closed"
+)
+
+// ServiceEvent is an EventBridge event emitted by an AWS service.
+type ServiceEvent struct {
+	ID         string          `json:"id"`
+	Source     string          `json:"source"`
+	DetailType string          `json:"detail-type"`
+	Account    string          `json:"account"`
+	Region     string          `json:"region"`
+	Time       string          `json:"time"`
+	Resources  []string        `json:"resources"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// RDSDetail of an RDS event.
+type RDSDetail struct {
+	EventCategories  []string `json:"EventCategories"`
+	SourceType       string   `json:"SourceType"`
+	SourceArn        string   `json:"SourceArn"`
+	SourceIdentifier string   `json:"SourceIdentifier"`
+	Message          string   `json:"Message"`
+	EventID          string   `json:"EventID"`
+}
+
+// ElastiCacheDetail of an ElastiCache event.
+type ElastiCacheDetail struct {
+	Message string `json:"message"`
+}
+
+// HealthDetail of an AWS Health event.
+type HealthDetail struct {
+	EventArn          string                   `json:"eventArn"`
+	Service           string                   `json:"service"`
+	EventTypeCode     string                   `json:"eventTypeCode"`
+	EventTypeCategory string                   `json:"eventTypeCategory"`
+	StatusCode        string                   `json:"statusCode"`
+	EventDescription  []HealthEventDescription `json:"eventDescription"`
+}
+
+// HealthEventDescription is the description of an AWS Health event in a given language.
+type HealthEventDescription struct {
+	Language          string `json:"language"`
+	LatestDescription string `json:"latestDescription"`
+}
+
+// Notice is a service event converted into an alarm against each of the affected resources.
+type Notice struct {
+	// Name identifies the type of event and is used in place of the alarm name.
+	Name string
+	// Reason is used as the reason for Kubernetes events.
+	Reason string
+	// Message describes what happened.
+	Message string
+	// State is "ALARM", or "OK" when the service reports the event has been resolved.
+	State string
+	// Resources are the ARNs of the affected resources.
+	Resources []string
+	// Timestamp is when the event occurred.
+	Timestamp time.Time
+}
+
+// Supported returns true if the event is from a service which can be converted into a notice.
+func (e *ServiceEvent) Supported() bool {
+	switch e.Source {
+	case SourceRDS, SourceElastiCache, SourceHealth:
+		return e.DetailType != ""
+	}
+
+	return false
+}
+
+// Parse the service event into a notice.
+func Parse(e *ServiceEvent) (*Notice, error) {
+	notice := &Notice{
+		Name:      e.DetailType,
+		State:     severity.StateAlarm,
+		Resources: e.Resources,
+	}
+
+	if e.Time != "" {
+		timestamp, err := time.Parse(time.RFC3339, e.Time)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse event time: %w", err)
+		}
+
+		notice.Timestamp = timestamp
+	}
+
+	switch e.Source {
+	case SourceRDS:
+		var detail RDSDetail
+
+		if err := json.Unmarshal(e.Detail, &detail); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rds detail: %w", err)
+		}
+
+		notice.Reason = "RDSEvent"
+		if len(detail.EventCategories) > 0 {
+			notice.Reason = "RDS" + Reason(detail.EventCategories[0])
+		}
+
+		if detail.EventID != "" {
+			notice.Name = detail.EventID
+		}
+
+		if detail.SourceArn != "" {
+			notice.Resources = []string{detail.SourceArn}
+		}
+
+		notice.Message = detail.Message
+	case SourceElastiCache:
+		var detail ElastiCacheDetail
+
+		if err := json.Unmarshal(e.Detail, &detail); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal elasticache detail: %w", err)
+		}
+
+		notice.Reason = Reason(e.DetailType)
+		notice.Message = detail.Message
+	case SourceHealth:
+		var detail HealthDetail
+
+		if err := json.Unmarshal(e.Detail, &detail); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal health detail: %w", err)
+		}
+
+		if detail.EventTypeCode != "" {
+			notice.Name = detail.EventTypeCode
+		}
+
+		notice.Reason = Reason(strings.ToLower(strings.TrimPrefix(notice.Name, "AWS_")))
+		notice.Message = detail.Description()
+
+		if detail.StatusCode == HealthStatusClosed {
+			notice.State = severity.StateOK
+		}
+	default:
+		return nil, fmt.Errorf("unsupported source: %s", e.Source)
+	}
+
+	if notice.Message == "" {
+		notice.Message = e.DetailType
+	}
+
+	notice.Message = cloudwatch.Truncate(notice.Message, cloudwatch.MessageLimit)
+
+	return notice, nil
+}
+
+// Description of the event, preferring English.
+func (d HealthDetail) Description() string {
+	for _, description := range d.EventDescription {
+		if strings.HasPrefix(description.Language, "en") {
+			return description.LatestDescription
+		}
+	}
+
+	if len(d.EventDescription) > 0 {
+		return d.EventDescription[0].LatestDescription
+	}
+
+	return ""
+}
+
+// Reason converts a phrase into an UpperCamelCase Kubernetes event reason eg. "configuration change" to "ConfigurationChange".
+func Reason(phrase string) string {
+	var b strings.Builder
+
+	words := strings.FieldsFunc(phrase, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package eventbridge
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/cloudwatch"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/severity"
+)
+
+func parseEvent(t *testing.T, data string) *ServiceEvent {
+	var event ServiceEvent
+	assert.NoError(t, json.Unmarshal([]byte(data), &event))
+	return &event
+}
+
+func TestParseRDS(t *testing.T) {
+	event := parseEvent(t, `{
+		"source": "aws.rds",
+		"detail-type": "RDS DB Instance Event",
+		"time": "2024-01-01T12:00:00Z",
+		"resources": ["arn:aws:rds:ap-southeast-2:123456789012:db:drupal"],
+		"detail": {
+			"EventCategories": ["failover"],
+			"SourceType": "DB_INSTANCE",
+			"SourceArn": "arn:aws:rds:ap-southeast-2:123456789012:db:drupal",
+			"Message": "A Multi-AZ failover has completed.",
+			"EventID": "RDS-EVENT-0049"
+		}
+	}`)
+	assert.True(t, event.Supported())
+
+	notice, err := Parse(event)
+	assert.NoError(t, err)
+	assert.Equal(t, &Notice{
+		Name:      "RDS-EVENT-0049",
+		Reason:    "RDSFailover",
+		Message:   "A Multi-AZ failover has completed.",
+		State:     severity.StateAlarm,
+		Resources: []string{"arn:aws:rds:ap-southeast-2:123456789012:db:drupal"},
+		Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}, notice)
+}
+
+func TestParseElastiCache(t *testing.T) {
+	notice, err := Parse(parseEvent(t, `{
+		"source": "aws.elasticache",
+		"detail-type": "ElastiCache Replication Group Failover",
+		"resources": ["arn:aws:elasticache:ap-southeast-2:123456789012:replicationgroup:drupal"],
+		"detail": {}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "ElastiCacheReplicationGroupFailover", notice.Reason)
+	assert.Equal(t, "ElastiCache Replication Group Failover", notice.Message)
+}
+
+func TestParseMessageLimit(t *testing.T) {
+	message := "é" + strings.Repeat("日本", cloudwatch.MessageLimit)
+
+	data, err := json.Marshal(map[string]any{
+		"source":      "aws.rds",
+		"detail-type": "RDS DB Instance Event",
+		"detail":      map[string]any{"Message": message, "EventID": "RDS-EVENT-0049"},
+	})
+	assert.NoError(t, err)
+
+	notice, err := Parse(parseEvent(t, string(data)))
+	assert.NoError(t, err)
+
+	// The message is truncated without splitting a character.
+	assert.LessOrEqual(t, len(notice.Message), cloudwatch.MessageLimit)
+	assert.True(t, utf8.ValidString(notice.Message))
+	assert.True(t, strings.HasPrefix(message, notice.Message))
+}
+
+func TestParseHealth(t *testing.T) {
+	event := parseEvent(t, `{
+		"source": "aws.health",
+		"detail-type": "AWS Health Event",
+		"resources": ["arn:aws:rds:ap-southeast-2:123456789012:db:drupal"],
+		"detail": {
+			"service": "RDS",
+			"eventTypeCode": "AWS_RDS_MAINTENANCE_SCHEDULED",
+			"statusCode": "closed",
+			"eventDescription": [
+				{"language": "fr_FR", "latestDescription": "Maintenance"},
+				{"language": "en_US", "latestDescription": "Maintenance is scheduled."}
+			]
+		}
+	}`)
+
+	notice, err := Parse(event)
+	assert.NoError(t, err)
+	assert.Equal(t, "AWS_RDS_MAINTENANCE_SCHEDULED", notice.Name)
+	assert.Equal(t, "RdsMaintenanceScheduled", notice.Reason)
+	assert.Equal(t, "Maintenance is scheduled.", notice.Message)
+	assert.Equal(t, severity.StateOK, notice.State)
+
+	assert.False(t, (&ServiceEvent{Source: "aws.cloudwatch"}).Supported())
+
+	_, err = Parse(&ServiceEvent{Source: "aws.cloudwatch"})
+	assert.Error(t, err)
+}
+
+type mockTagsClient struct {
+	calls int
+}
+
+func (m *mockTagsClient) GetResources(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput, optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+	m.calls++
+
+	var out resourcegroupstaggingapi.GetResourcesOutput
+
+	for _, arn := range params.ResourceARNList {
+		out.ResourceTagMappingList = append(out.ResourceTagMappingList, types.ResourceTagMapping{
+			ResourceARN: aws.String(arn),
+			Tags: []types.Tag{
+				{Key: aws.String("skpr.io/k8s-event-name"), Value: aws.String("prod")},
+			},
+		})
+	}
+
+	return &out, nil
+}
+
+func TestTags(t *testing.T) {
+	client := &mockTagsClient{}
+
+	tags, err := Tags(context.Background(), client, []string{"arn:aws:rds:ap-southeast-2:123456789012:db:drupal", "i-123456"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"arn:aws:rds:ap-southeast-2:123456789012:db:drupal": {"skpr.io/k8s-event-name": "prod"},
+	}, tags)
+	assert.Equal(t, 1, client.calls)
+}
@@ -0,0 +1,72 @@
+package eventbridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+)
+
+// ARNLimit is the maximum number of ARNs which can be looked up in a single request.
+const ARNLimit = 100
+
+// TagsClientInterface for looking up the tags of resources from any service.
+type TagsClientInterface interface {
+	GetResources(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput, optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error)
+}
+
+// Tags returns the tags for each of the resources, keyed by ARN. Resources which are not ARNs are ignored.
+func Tags(ctx context.Context, client TagsClientInterface, resources []string) (map[string]map[string]string, error) {
+	var arns []string
+
+	for _, resource := range resources {
+		if strings.HasPrefix(resource, "arn:") {
+			arns = append(arns, resource)
+		}
+	}
+
+	tags := make(map[string]map[string]string, len(arns))
+
+	for start := 0; start < len(arns); start += ARNLimit {
+		end := min(start+ARNLimit, len(arns))
+
+		var token *string
+
+		for {
+			resp, err := client.GetResources(ctx, &resourcegroupstaggingapi.GetResourcesInput{
+				ResourceARNList: arns[start:end],
+				PaginationToken: token,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get resources: %w", err)
+			}
+
+			for _, mapping := range resp.ResourceTagMappingList {
+				if mapping.ResourceARN == nil {
+					continue
+				}
+
+				m := make(map[string]string, len(mapping.Tags))
+
+				for _, tag := range mapping.Tags {
+					if tag.Key == nil || tag.Value == nil {
+						continue
+					}
+
+					m[*tag.Key] = *tag.Value
+				}
+
+				tags[*mapping.ResourceARN] = m
+			}
+
+			if resp.PaginationToken == nil || *resp.PaginationToken == "" {
+				break
+			}
+
+			token = resp.PaginationToken
+		}
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,134 @@
+package flap
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultWindow is the period which transitions are counted over.
+const DefaultWindow = 10 * time.Minute
+
+// Decision made after recording a transition.
+type Decision string
+
+const (
+	// DecisionEmit means the event should be emitted as normal.
+	DecisionEmit Decision = "emit"
+	// DecisionFlapping means the alarm has started flapping and a single flapping event should be emitted.
+	DecisionFlapping Decision = "flapping"
+	// DecisionHold means the alarm is still flapping and the event should be held.
+	DecisionHold Decision = "hold"
+)
+
+// State of each alarm associated with a target, keyed by alarm name.
+type State map[string]AlarmState
+
+// AlarmState tracks recent transitions for a single alarm.
+type AlarmState struct {
+	Transitions []time.Time `json:"transitions,omitempty"`
+	Flapping    bool        `json:"flapping,omitempty"`
+	// Changed is when the alarm changed to the state of the last recorded transition, so a retry is not counted twice.
+	Changed time.Time `json:"changed,omitempty"`
+	// Decision made for the last recorded transition, which is repeated for a retry.
+	Decision Decision `json:"decision,omitempty"`
+}
+
+// Detector determines if an alarm is flapping.
+type Detector struct {
+	// Threshold is the number of transitions within the window before an alarm is considered flapping. Zero disables detection.
+	Threshold int
+	// Window is the period which transitions are counted over.
+	Window time.Duration
+}
+
+// Enabled returns true if flap detection has been configured.
+func (d Detector) Enabled() bool {
+	return d.Threshold > 0
+}
+
+// Record a transition for the alarm, which changed state at changed, and decide what to do with the event. A transition
+// which was already recorded, because its event failed and is being retried, is not counted again and gets the same
+// decision. Alarms without transitions in the window are dropped from the state so it does not grow with alarms which
+// no longer fire.
+func (d Detector) Record(state State, alarm string, changed, now time.Time) Decision {
+	window := d.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	for name, s := range state {
+		if s.Transitions = recent(s.Transitions, now, window); len(s.Transitions) == 0 {
+			delete(state, name)
+			continue
+		}
+
+		state[name] = s
+	}
+
+	current := state[alarm]
+
+	if !changed.IsZero() && changed.Equal(current.Changed) && current.Decision != "" && len(current.Transitions) > 0 {
+		return current.Decision
+	}
+
+	current.Transitions = append(current.Transitions, now)
+
+	decision := DecisionEmit
+
+	switch {
+	case len(current.Transitions) >= d.Threshold && current.Flapping:
+		decision = DecisionHold
+	case len(current.Transitions) >= d.Threshold:
+		current.Flapping = true
+		decision = DecisionFlapping
+	default:
+		// The alarm has settled.
+		current.Flapping = false
+	}
+
+	current.Changed = changed
+	current.Decision = decision
+
+	state[alarm] = current
+
+	return decision
+}
+
+// recent returns the transitions within the window.
+func recent(transitions []time.Time, now time.Time, window time.Duration) []time.Time {
+	var result []time.Time
+
+	for _, t := range transitions {
+		if now.Sub(t) < window {
+			result = append(result, t)
+		}
+	}
+
+	return result
+}
+
+// Decode the state from an annotation value.
+func Decode(value string) (State, error) {
+	state := make(State)
+
+	if value == "" {
+		return state, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal flap state: %w", err)
+	}
+
+	return state, nil
+}
+
+// Encode the state as an annotation value.
+func (s State) Encode() (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal flap state: %w", err)
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,72 @@
+package flap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	var (
+		detector = Detector{Threshold: 3, Window: 10 * time.Minute}
+		state    = make(State)
+		now      = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	assert.Equal(t, DecisionEmit, detector.Record(state, "foo", now, now))
+	assert.Equal(t, DecisionEmit, detector.Record(state, "foo", now.Add(time.Minute), now.Add(time.Minute)))
+	assert.Equal(t, DecisionFlapping, detector.Record(state, "foo", now.Add(2*time.Minute), now.Add(2*time.Minute)))
+
+	// A retry of the same transition is not counted again, and is given the same decision.
+	assert.Equal(t, DecisionFlapping, detector.Record(state, "foo", now.Add(2*time.Minute), now.Add(2*time.Minute+time.Second)))
+	assert.Len(t, state["foo"].Transitions, 3)
+
+	assert.Equal(t, DecisionHold, detector.Record(state, "foo", now.Add(3*time.Minute), now.Add(3*time.Minute)))
+
+	// Other alarms are tracked separately.
+	assert.Equal(t, DecisionEmit, detector.Record(state, "bar", now.Add(3*time.Minute), now.Add(3*time.Minute)))
+
+	// Once the transitions fall out of the window the alarm has settled.
+	assert.Equal(t, DecisionEmit, detector.Record(state, "foo", now.Add(time.Hour), now.Add(time.Hour)))
+	assert.False(t, state["foo"].Flapping)
+
+	// Alarms which no longer fire are pruned.
+	assert.NotContains(t, state, "bar")
+}
+
+func TestRecordRetries(t *testing.T) {
+	var (
+		detector = Detector{Threshold: 2, Window: 10 * time.Minute}
+		state    = make(State)
+		changed  = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	// Retries alone never make an alarm flap.
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, DecisionEmit, detector.Record(state, "foo", changed, changed.Add(time.Duration(i)*time.Second)))
+	}
+
+	// Transitions without a state timestamp are always counted.
+	assert.Equal(t, DecisionFlapping, detector.Record(state, "foo", time.Time{}, changed.Add(time.Minute)))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	state := State{
+		"foo": {
+			Transitions: []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			Flapping:    true,
+		},
+	}
+
+	value, err := state.Encode()
+	assert.NoError(t, err)
+
+	decoded, err := Decode(value)
+	assert.NoError(t, err)
+	assert.Equal(t, state, decoded)
+
+	decoded, err = Decode("")
+	assert.NoError(t, err)
+	assert.Empty(t, decoded)
+}
@@ -0,0 +1,757 @@
+package forwarder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/action"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/allow"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/flap"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/metrics"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/report"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/resolver"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/rollout"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/severity"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/suppress"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/tracing"
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+const (
+	// SourceComponent is the component which events are reported by.
+	SourceComponent = "aws-cloudwatch-alarm"
+	// ReasonFlapping is the reason used for events when an alarm starts flapping.
+	ReasonFlapping = "Flapping"
+	// DefaultRateWindow is the period which the namespace rate limit is applied over.
+	DefaultRateWindow = time.Minute
+
+	// StaleMark creates stale events with an annotation.
+	StaleMark = "mark"
+	// StaleDrop discards stale events.
+	StaleDrop = "drop"
+
+	// ResolveEvent leaves Warning events alone when the alarm returns to OK. The recovery event is still created.
+	ResolveEvent = "event"
+	// ResolveDelete deletes the alarm's Warning events when it returns to OK.
+	ResolveDelete = "delete"
+	// ResolveAnnotate annotates the alarm's Warning events with the resolution time when it returns to OK.
+	ResolveAnnotate = "annotate"
+
+	// ReasonAction is the reason used for audit events when a remediation action is executed.
+	ReasonAction = "RemediationAction"
+	// ReasonActionDryRun is the reason used for audit events when a remediation action would have been executed.
+	ReasonActionDryRun = "RemediationDryRun"
+)
+
+// Alarm which will be forwarded to Kubernetes as events.
+type Alarm struct {
+	Name        string
+	ARN         string
+	State       string
+	Description string
+	Severity    severity.Severity
+	Target      target.Target
+	// Timestamp is when the alarm changed to its current state.
+	Timestamp time.Time
+	// PreviousTimestamp is when the alarm changed to its previous state.
+	PreviousTimestamp time.Time
+	// Annotations added to each event eg. the datapoints which caused the alarm to change state.
+	Annotations map[string]string
+}
+
+// Options for forwarding alarms.
+type Options struct {
+	// Fallback determines what happens when the target object is missing.
+	Fallback string
+	// Policy maps severity and alarm state to an event type.
+	Policy severity.Policy
+	// Checker determines if events should be suppressed.
+	Checker *suppress.Checker
+	// Detector determines if an alarm is flapping.
+	Detector flap.Detector
+	// RateLimit is the maximum number of events which can be created in a namespace per RateWindow. Zero disables the limit.
+	RateLimit int
+	// RateWindow is the period which the rate limit is applied over.
+	RateWindow time.Duration
+	// StalenessLimit is the maximum delivery lag before an event is considered stale. Zero disables the limit.
+	StalenessLimit time.Duration
+	// StalenessAction determines what happens to stale events: "mark" (default) or "drop".
+	StalenessAction string
+	// Allow restricts the namespaces and kinds which alarms can write to.
+	Allow *allow.Checker
+	// ClusterNamespace is the namespace which events for cluster-scoped objects are created in.
+	ClusterNamespace string
+	// ResolveAction determines what happens to Warning events when the alarm returns to OK: "event" (default), "delete"
+	// or "annotate".
+	ResolveAction string
+	// Actions configures which remediation actions can be executed against targets.
+	Actions action.Options
+	// Rollouts correlates alarms with the last rollout of the target. Nil disables correlation.
+	Rollouts *rollout.Finder
+}
+
+// Forwarder converts CloudWatch Alarms into Kubernetes events.
+type Forwarder struct {
+	clientset kubernetes.Interface
+	resolver  *resolver.Resolver
+	options   Options
+	now       func() time.Time
+}
+
+// New creates a new forwarder.
+func New(clientset kubernetes.Interface, resolver *resolver.Resolver, options Options) *Forwarder {
+	if options.RateWindow <= 0 {
+		options.RateWindow = DefaultRateWindow
+	}
+
+	if options.ClusterNamespace == "" {
+		options.ClusterNamespace = metav1.NamespaceDefault
+	}
+
+	return &Forwarder{
+		clientset: clientset,
+		resolver:  resolver,
+		options:   options,
+		now:       time.Now,
+	}
+}
+
+// delivery is a single event which will be created.
+type delivery struct {
+	// object is nil when the event is associated with a fallback.
+	object    *unstructured.Unstructured
+	ref       corev1.ObjectReference
+	reason    string
+	message   string
+	eventType string
+	// annotations added to the event eg. the last rollout of the object.
+	annotations map[string]string
+}
+
+// Forward the alarm to Kubernetes as events.
+func (f *Forwarder) Forward(ctx context.Context, alarm Alarm) error {
+	var (
+		logger   = logging.FromContext(ctx)
+		recorder = metrics.FromContext(ctx)
+		rep      = report.FromContext(ctx)
+	)
+
+	// The action is validated before any events are created so a bad tag never leaves a partial forward to be retried.
+	var remediation *action.Action
+
+	if alarm.State == severity.StateAlarm && alarm.Target.Action != "" {
+		a, err := action.Parse(alarm.Target.Action)
+		if err != nil {
+			return fmt.Errorf("failed to parse action: %w", err)
+		}
+
+		remediation = &a
+	}
+
+	first, last := f.timestamps(alarm)
+
+	lag := f.now().Sub(last)
+
+	logger.Info("Calculated delivery lag", "lag", lag.String())
+	recorder.Duration(metrics.DeliveryLag, lag)
+
+	stale := f.options.StalenessLimit > 0 && lag > f.options.StalenessLimit
+
+	if stale && f.options.StalenessAction == StaleDrop {
+		logger.Warn("Dropping stale event", "outcome", "suppressed", "lag", lag.String(), "limit", f.options.StalenessLimit.String())
+		recorder.Add(metrics.EventsSuppressed, 1)
+		rep.Decision(report.OutcomeSuppressed, "stale", "")
+		return nil
+	}
+
+	namespaced, err := f.resolver.Namespaced(alarm.Target)
+	if err != nil {
+		return err
+	}
+
+	// Events for cluster-scoped objects are created in a fixed namespace, matching the Kubernetes event recorder.
+	eventNamespace := alarm.Target.Namespace
+
+	if !namespaced {
+		alarm.Target.Namespace = ""
+		eventNamespace = f.options.ClusterNamespace
+	}
+
+	if reason, ok := f.options.Checker.Window(eventNamespace); ok {
+		logger.Info("Suppressed event", "outcome", "suppressed", "reason", reason)
+		recorder.Add(metrics.EventsSuppressed, 1)
+		rep.Decision(report.OutcomeSuppressed, reason, "")
+		return nil
+	}
+
+	if err := f.options.Allow.Target(alarm.Target.Cluster, eventNamespace, alarm.Target.APIGroup, alarm.Target.Kind); err != nil {
+		return f.deny(ctx, err)
+	}
+
+	namespace, err := f.resolver.Namespace(ctx, eventNamespace)
+	if err != nil {
+		return err
+	}
+
+	if err := f.options.Allow.Namespace(alarm.Target.Cluster, namespace, alarm.ARN, alarm.Name); err != nil {
+		return f.deny(ctx, err)
+	}
+
+	if reason, ok := f.options.Checker.Muted(ctx, namespace, alarm.Name); ok {
+		logger.Info("Suppressed event", "outcome", "suppressed", "reason", "namespace "+reason)
+		recorder.Add(metrics.EventsSuppressed, 1)
+		rep.Decision(report.OutcomeSuppressed, "namespace "+reason, "")
+		return nil
+	}
+
+	stop := recorder.Stage(metrics.StageObjectLookup)
+	spanCtx, span := tracing.Start(ctx, metrics.StageObjectLookup)
+
+	deliveries, notFound, err := f.plan(spanCtx, alarm)
+	tracing.End(span, err)
+	if errors.Is(err, resolver.ErrLimit) {
+		// The selector will match too many objects on retry as well, so the event is denied rather than failed.
+		return f.deny(ctx, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	stop()
+
+	// Resolve before creating so the recovery event is never resolved itself, and even when the recovery event is held
+	// because the alarm is flapping, so an alarm which settles to OK still resolves its events.
+	if alarm.State == severity.StateOK {
+		if err := f.resolve(ctx, eventNamespace, alarm.Name); err != nil {
+			return err
+		}
+	}
+
+	if len(deliveries) == 0 && notFound {
+		logger.Warn("Dropping event because the target was not found", "outcome", "dropped", "target_not_found", true)
+		rep.Decision(report.OutcomeDropped, "target not found", "")
+		return nil
+	}
+
+	if len(deliveries) == 0 {
+		logger.Info("All target objects are muted or held", "outcome", "suppressed")
+		return nil
+	}
+
+	deliveries, err = f.limit(ctx, eventNamespace, deliveries)
+	if err != nil {
+		return err
+	}
+
+	if alarm.State == severity.StateAlarm && f.options.Rollouts != nil {
+		f.correlate(ctx, deliveries)
+	}
+
+	defer recorder.Stage(metrics.StageCreate)()
+
+	ctx, span = tracing.Start(ctx, metrics.StageCreate, trace.WithAttributes(attribute.Int("events", len(deliveries))))
+	defer span.End()
+
+	for _, d := range deliveries {
+		logger.Info("Marshalling to Kubernetes event", "kind", d.ref.Kind, "name", d.ref.Name)
+
+		object := &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:    eventNamespace,
+				GenerateName: "aws-cloudwatch-alarm-",
+				Annotations: map[string]string{
+					annotation.KeyCloudWatchAlarmName: alarm.Name,
+					annotation.KeySeverity:            string(alarm.Severity),
+					annotation.KeyDeliveryLag:         lag.String(),
+				},
+			},
+			InvolvedObject: d.ref,
+			Type:           d.eventType,
+			Reason:         d.reason,
+			Message:        d.message,
+			FirstTimestamp: metav1.NewTime(first),
+			LastTimestamp:  metav1.NewTime(last),
+			Source: corev1.EventSource{
+				Component: SourceComponent,
+			},
+		}
+
+		for _, annotations := range []map[string]string{d.annotations, alarm.Annotations} {
+			for key, value := range annotations {
+				if _, ok := object.ObjectMeta.Annotations[key]; !ok {
+					object.ObjectMeta.Annotations[key] = value
+				}
+			}
+		}
+
+		if notFound {
+			object.ObjectMeta.Annotations[annotation.KeyTargetNotFound] = "true"
+		}
+
+		if stale {
+			object.ObjectMeta.Annotations[annotation.KeyStale] = "true"
+		}
+
+		created, err := f.clientset.CoreV1().Events(object.ObjectMeta.Namespace).Create(ctx, object, metav1.CreateOptions{})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to create event: %w", err)
+		}
+
+		logger.Info("Created event", "outcome", "created", "event", created.Name, "kind", d.ref.Kind, "name", d.ref.Name)
+		recorder.Add(metrics.EventsCreated, 1)
+		rep.Event(report.Event{
+			Namespace: created.Namespace,
+			Name:      created.Name,
+			UID:       string(created.UID),
+			Operation: report.OperationCreated,
+			Object:    objectName(d.ref.Kind, d.ref.Name),
+		})
+	}
+
+	if remediation != nil {
+		f.act(ctx, *remediation, alarm, eventNamespace, deliveries, stale)
+	}
+
+	return nil
+}
+
+// correlate adds the last rollout of each object to its event so responders can see if the alarm followed a deploy.
+// Correlation is best effort and never prevents the event from being created.
+func (f *Forwarder) correlate(ctx context.Context, deliveries []delivery) {
+	var (
+		logger  = logging.FromContext(ctx)
+		now     = f.now()
+		session = f.options.Rollouts.Session()
+	)
+
+	for i, d := range deliveries {
+		if d.object == nil {
+			continue
+		}
+
+		latest, err := session.Latest(ctx, d.object, now)
+		if err != nil {
+			logger.Warn("Failed to correlate rollout", "kind", d.ref.Kind, "name", d.ref.Name, "error", err.Error())
+			continue
+		}
+
+		if latest == nil {
+			continue
+		}
+
+		logger.Info("Correlated rollout", "kind", d.ref.Kind, "name", d.ref.Name, "rollout", latest.Object, "revision", latest.Revision)
+
+		deliveries[i].message = fmt.Sprintf("%s; %s", d.message, latest.Summary(now))
+		deliveries[i].annotations = latest.Annotations()
+	}
+}
+
+// act executes the target's remediation action against each object and creates an audit event for it.
+// Objects which are not permitted, are within the cooldown or have started flapping are skipped, as are all objects when
+// the alarm is stale, since a replayed alarm no longer reflects the workload. The alarm events have already been
+// created, so failures are recorded rather than returned, which would create them again on retry.
+func (f *Forwarder) act(ctx context.Context, a action.Action, alarm Alarm, namespace string, deliveries []delivery, stale bool) {
+	var (
+		logger   = logging.FromContext(ctx)
+		recorder = metrics.FromContext(ctx)
+		rep      = report.FromContext(ctx)
+		now      = f.now()
+	)
+
+	failed := func(object string, err error) {
+		logger.Error("Action failed", "outcome", "failed", "action", a.String(), "object", object, "error_class", metrics.Classify(err), "error", err.Error())
+		recorder.Add(metrics.ActionsFailed, 1)
+		rep.Action(report.Action{Action: a.String(), Object: object, Error: err.Error()})
+	}
+
+	for _, d := range deliveries {
+		if d.object == nil || d.reason == ReasonFlapping {
+			continue
+		}
+
+		var (
+			object = d.object
+			gvk    = object.GroupVersionKind()
+		)
+
+		if stale {
+			logger.Warn("Skipping action because the alarm is stale", "outcome", "skipped", "action", a.String(), "kind", gvk.Kind, "name", object.GetName())
+			recorder.Add(metrics.ActionsSkipped, 1)
+			rep.Action(report.Action{Action: a.String(), Object: objectName(gvk.Kind, object.GetName()), Skipped: "stale"})
+			continue
+		}
+
+		if err := f.options.Actions.Allowed(a, object, gvk.Group, gvk.Kind); err != nil {
+			logger.Warn("Skipping action", "outcome", "denied", "action", a.String(), "reason", err.Error())
+			recorder.Add(metrics.ActionsSkipped, 1)
+			rep.Action(report.Action{Action: a.String(), Object: objectName(gvk.Kind, object.GetName()), Skipped: err.Error()})
+			continue
+		}
+
+		if last, ok := f.options.Actions.Cooling(object, now); ok {
+			logger.Info("Skipping action because of cooldown", "outcome", "skipped", "action", a.String(), "kind", gvk.Kind, "name", object.GetName(),
+				"last", last.Format(time.RFC3339))
+			recorder.Add(metrics.ActionsSkipped, 1)
+			rep.Action(report.Action{Action: a.String(), Object: objectName(gvk.Kind, object.GetName()), Skipped: "cooldown"})
+			continue
+		}
+
+		patch, err := a.Patch(object, now)
+		if err != nil {
+			failed(objectName(gvk.Kind, object.GetName()), fmt.Errorf("failed to plan action: %w", err))
+			continue
+		}
+
+		reason, message := ReasonAction, fmt.Sprintf("Executed %s triggered by CloudWatch Alarm %s", a, alarm.Name)
+
+		// Dry runs only record the cooldown so they are skipped the same way as the action would have been.
+		if f.options.Actions.DryRun {
+			reason, message, patch = ReasonActionDryRun, fmt.Sprintf("Dry run of %s triggered by CloudWatch Alarm %s", a, alarm.Name), action.Cooldown(now)
+		}
+
+		if err := f.resolver.Patch(ctx, object, patch); err != nil {
+			failed(objectName(gvk.Kind, object.GetName()), fmt.Errorf("failed to execute action %s: %w", a, err))
+			continue
+		}
+
+		created, err := f.clientset.CoreV1().Events(namespace).Create(ctx, &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:    namespace,
+				GenerateName: "aws-cloudwatch-alarm-",
+				Annotations: map[string]string{
+					annotation.KeyCloudWatchAlarmName: alarm.Name,
+					annotation.KeyAction:              a.String(),
+				},
+			},
+			InvolvedObject: d.ref,
+			Type:           corev1.EventTypeNormal,
+			Reason:         reason,
+			Message:        message,
+			FirstTimestamp: metav1.NewTime(now),
+			LastTimestamp:  metav1.NewTime(now),
+			Source: corev1.EventSource{
+				Component: SourceComponent,
+			},
+		}, metav1.CreateOptions{})
+
+		logger.Info("Executed action", "outcome", "executed", "action", a.String(), "dry_run", f.options.Actions.DryRun, "kind", gvk.Kind, "name", object.GetName())
+		recorder.Add(metrics.ActionsExecuted, 1)
+		rep.Action(report.Action{Action: a.String(), Object: objectName(gvk.Kind, object.GetName()), DryRun: f.options.Actions.DryRun})
+
+		// The action was executed and its cooldown recorded, so a missing audit event is only logged.
+		if err != nil {
+			logger.Error("Failed to create audit event", "action", a.String(), "kind", gvk.Kind, "name", object.GetName(), "error_class", metrics.Classify(err), "error", err.Error())
+			continue
+		}
+
+		rep.Event(report.Event{
+			Namespace: created.Namespace,
+			Name:      created.Name,
+			UID:       string(created.UID),
+			Operation: report.OperationCreated,
+			Object:    objectName(gvk.Kind, object.GetName()),
+		})
+	}
+}
+
+// resolve the Warning events previously created for the alarm using the configured action.
+func (f *Forwarder) resolve(ctx context.Context, namespace, alarm string) error {
+	var (
+		logger   = logging.FromContext(ctx)
+		recorder = metrics.FromContext(ctx)
+	)
+
+	switch f.options.ResolveAction {
+	case ResolveEvent, "":
+		return nil
+	case ResolveDelete, ResolveAnnotate:
+	default:
+		return fmt.Errorf("unknown resolve action: %s", f.options.ResolveAction)
+	}
+
+	list, err := f.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("source", SourceComponent).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list events: %w", err)
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{
+				annotation.KeyResolvedAt: f.now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+
+	for _, event := range list.Items {
+		if event.Type != corev1.EventTypeWarning || event.Annotations[annotation.KeyCloudWatchAlarmName] != alarm {
+			continue
+		}
+
+		if _, ok := event.Annotations[annotation.KeyResolvedAt]; ok {
+			continue
+		}
+
+		switch f.options.ResolveAction {
+		case ResolveDelete:
+			err = f.clientset.CoreV1().Events(namespace).Delete(ctx, event.Name, metav1.DeleteOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+		case ResolveAnnotate:
+			_, err = f.clientset.CoreV1().Events(namespace).Patch(ctx, event.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to resolve event %s: %w", event.Name, err)
+		}
+
+		logger.Info("Resolved event", "outcome", "resolved", "event", event.Name, "action", f.options.ResolveAction)
+		recorder.Add(metrics.EventsResolved, 1)
+
+		operation := report.OperationAnnotated
+		if f.options.ResolveAction == ResolveDelete {
+			operation = report.OperationDeleted
+		}
+
+		report.FromContext(ctx).Event(report.Event{
+			Namespace: event.Namespace,
+			Name:      event.Name,
+			UID:       string(event.UID),
+			Operation: operation,
+			Object:    objectName(event.InvolvedObject.Kind, event.InvolvedObject.Name),
+		})
+	}
+
+	return nil
+}
+
+// deny logs the reason the alarm is not allowed to write to the target. Denials are not retried.
+func (f *Forwarder) deny(ctx context.Context, err error) error {
+	logging.FromContext(ctx).Warn("Denied event", "outcome", "denied", "reason", err.Error())
+	metrics.FromContext(ctx).Add(metrics.EventsDenied, 1)
+	report.FromContext(ctx).Decision(report.OutcomeDenied, err.Error(), "")
+
+	return nil
+}
+
+// timestamps returns the first and last timestamps for the event. Recovery events span from when the alarm was
+// triggered until it returned to OK. Missing timestamps default to now.
+func (f *Forwarder) timestamps(alarm Alarm) (time.Time, time.Time) {
+	last := alarm.Timestamp
+	if last.IsZero() {
+		last = f.now()
+	}
+
+	first := last
+
+	if alarm.State == severity.StateOK && !alarm.PreviousTimestamp.IsZero() {
+		first = alarm.PreviousTimestamp
+	}
+
+	return first, last
+}
+
+// plan the events which will be created, applying the configured fallback if the target object is missing and
+// skipping objects which have muted the alarm or are flapping.
+func (f *Forwarder) plan(ctx context.Context, alarm Alarm) ([]delivery, bool, error) {
+	var (
+		logger   = logging.FromContext(ctx)
+		recorder = metrics.FromContext(ctx)
+		rep      = report.FromContext(ctx)
+	)
+
+	standard := delivery{
+		reason:    alarm.Target.Reason,
+		message:   alarm.Description,
+		eventType: f.options.Policy.EventType(alarm.Severity, alarm.State),
+	}
+
+	objects, err := f.resolver.Resolve(ctx, alarm.Target)
+	if errors.Is(err, resolver.ErrNotFound) {
+		logger.Warn("Target not found, applying fallback", "error", err.Error(), "fallback", f.options.Fallback)
+
+		ref, err := f.resolver.Fallback(ctx, alarm.Target, f.options.Fallback)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to apply fallback for missing target: %w", err)
+		}
+
+		if ref == nil {
+			return nil, true, nil
+		}
+
+		standard.ref = *ref
+
+		return []delivery{standard}, true, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve target objects: %w", err)
+	}
+
+	// Owners are checked before they are read, and the objects themselves before events are created for them.
+	allowed := func(group, kind, name string) bool {
+		if err := f.options.Allow.Kind(alarm.Target.Cluster, group, kind); err != nil {
+			logger.Warn("Denied event", "outcome", "denied", "reason", err.Error(), "kind", kind, "name", name)
+			recorder.Add(metrics.EventsDenied, 1)
+			rep.Decision(report.OutcomeDenied, err.Error(), objectName(kind, name))
+			return false
+		}
+
+		return true
+	}
+
+	objects, err = f.resolver.Owners(ctx, alarm.Target, objects, allowed)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve target owners: %w", err)
+	}
+
+	var (
+		deliveries   []delivery
+		suppressed   int
+		deduplicated int
+	)
+
+	for _, object := range objects {
+		if !allowed(object.GroupVersionKind().Group, object.GetKind(), object.GetName()) {
+			continue
+		}
+
+		if reason, ok := f.options.Checker.Muted(ctx, object, alarm.Name); ok {
+			logger.Info("Suppressing event", "outcome", "suppressed", "reason", reason)
+			rep.Decision(report.OutcomeSuppressed, reason, objectName(object.GetKind(), object.GetName()))
+			suppressed++
+			continue
+		}
+
+		d := standard
+		d.object = object
+		d.ref = resolver.Reference(object)
+
+		if f.options.Detector.Enabled() {
+			decision, err := f.detectFlapping(ctx, object, alarm)
+			if err != nil {
+				return nil, false, err
+			}
+
+			switch decision {
+			case flap.DecisionHold:
+				logger.Info("Holding event because the alarm is flapping", "outcome", "deduplicated", "kind", object.GetKind(), "name", object.GetName())
+				rep.Decision(report.OutcomeDeduplicated, "flapping", objectName(object.GetKind(), object.GetName()))
+				deduplicated++
+				continue
+			case flap.DecisionFlapping:
+				logger.Warn("Alarm started flapping", "kind", object.GetKind(), "name", object.GetName())
+				rep.Decision(report.OutcomeFlapping, "alarm started flapping", objectName(object.GetKind(), object.GetName()))
+				d.reason = ReasonFlapping
+				d.eventType = corev1.EventTypeWarning
+				d.message = fmt.Sprintf("CloudWatch Alarm %s is flapping with %d or more transitions in %s. Further events are held until it settles.",
+					alarm.Name, f.options.Detector.Threshold, f.options.Detector.Window)
+			}
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if suppressed > 0 || deduplicated > 0 {
+		logger.Info("Filtered target objects", "suppressed", suppressed, "deduplicated", deduplicated)
+	}
+
+	recorder.Add(metrics.EventsSuppressed, suppressed)
+	recorder.Add(metrics.EventsDeduplicated, deduplicated)
+
+	return deliveries, false, nil
+}
+
+// detectFlapping records the transition in the object's annotations and returns the decision. The transition is stored
+// before the event is created, so it is identified by the alarm's state timestamp to not count it again on retry.
+func (f *Forwarder) detectFlapping(ctx context.Context, object *unstructured.Unstructured, alarm Alarm) (flap.Decision, error) {
+	state, err := flap.Decode(object.GetAnnotations()[annotation.KeyFlapState])
+	if err != nil {
+		return "", err
+	}
+
+	decision := f.options.Detector.Record(state, alarm.Name, alarm.Timestamp, f.now())
+
+	value, err := state.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	if err := f.resolver.Annotate(ctx, object, annotation.KeyFlapState, value); err != nil {
+		return "", fmt.Errorf("failed to store flap state: %w", err)
+	}
+
+	return decision, nil
+}
+
+// limit the number of events which can be created in a namespace within the rate window.
+func (f *Forwarder) limit(ctx context.Context, namespace string, deliveries []delivery) ([]delivery, error) {
+	if f.options.RateLimit <= 0 {
+		return deliveries, nil
+	}
+
+	list, err := f.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("source", SourceComponent).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	var (
+		since  = f.now().Add(-f.options.RateWindow)
+		recent int
+	)
+
+	for _, event := range list.Items {
+		if event.Source.Component == SourceComponent && event.CreationTimestamp.After(since) {
+			recent++
+		}
+	}
+
+	remaining := f.options.RateLimit - recent
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	if len(deliveries) > remaining {
+		logging.FromContext(ctx).Warn("Rate limited events", "outcome", "suppressed", "limited", len(deliveries)-remaining, "namespace", namespace,
+			"recent", recent, "window", f.options.RateWindow.String())
+		metrics.FromContext(ctx).Add(metrics.EventsSuppressed, len(deliveries)-remaining)
+
+		for _, d := range deliveries[remaining:] {
+			report.FromContext(ctx).Decision(report.OutcomeSuppressed, "rate limited", objectName(d.ref.Kind, d.ref.Name))
+		}
+
+		return deliveries[:remaining], nil
+	}
+
+	return deliveries, nil
+}
+
+// objectName formats the kind and name of an object for reports eg. "Pod/drupal".
+func objectName(kind, name string) string {
+	return fmt.Sprintf("%s/%s", kind, name)
+}
@@ -0,0 +1,728 @@
+package forwarder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/action"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/allow"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/flap"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/metrics"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/report"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/resolver"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/rollout"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/severity"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/suppress"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+var (
+	podGVK        = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	nodeGVK       = schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+	deploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	namespaceGVK  = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+)
+
+func newUnstructured(gvk schema.GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(gvk)
+	object.SetNamespace(namespace)
+	object.SetName(name)
+	return object
+}
+
+func newForwarder(t *testing.T, options Options, objects ...runtime.Object) (*Forwarder, *fake.Clientset) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(podGVK, meta.RESTScopeNamespace)
+	mapper.Add(nodeGVK, meta.RESTScopeRoot)
+	mapper.Add(deploymentGVK, meta.RESTScopeNamespace)
+
+	// The test namespace is created unless it is provided eg. with annotations.
+	if !slices.ContainsFunc(objects, func(object runtime.Object) bool {
+		return object.GetObjectKind().GroupVersionKind() == namespaceGVK && object.(metav1.Object).GetName() == "test"
+	}) {
+		objects = append(objects, newUnstructured(namespaceGVK, "", "test"))
+	}
+
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+
+	if options.Checker == nil {
+		checker, err := suppress.New("")
+		assert.NoError(t, err)
+
+		options.Checker = checker
+	}
+
+	clientset := fake.NewSimpleClientset()
+
+	// The fake clientset does not generate names.
+	var generated int
+
+	clientset.PrependReactor("create", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		event := action.(k8stesting.CreateAction).GetObject().(*corev1.Event)
+
+		if event.Name == "" {
+			generated++
+			event.Name = fmt.Sprintf("%s%d", event.GenerateName, generated)
+		}
+
+		return false, nil, nil
+	})
+
+	return New(clientset, resolver.New(client, mapper, resolver.Options{}), options), clientset
+}
+
+func newAlarm() Alarm {
+	return Alarm{
+		Name:        "drupal-5xx",
+		State:       severity.StateAlarm,
+		Description: "This is a test",
+		Severity:    severity.Warning,
+		Target: target.Target{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Namespace:  "test",
+			Name:       "drupal",
+			Reason:     "HighErrorRate",
+		},
+	}
+}
+
+func listEvents(t *testing.T, clientset *fake.Clientset) []corev1.Event {
+	list, err := clientset.CoreV1().Events("test").List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	return list.Items
+}
+
+func TestForward(t *testing.T) {
+	f, clientset := newForwarder(t, Options{}, newUnstructured(podGVK, "test", "drupal"))
+
+	err := f.Forward(context.TODO(), newAlarm())
+	assert.NoError(t, err)
+
+	events := listEvents(t, clientset)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "HighErrorRate", events[0].Reason)
+	assert.Equal(t, corev1.EventTypeWarning, events[0].Type)
+	assert.Equal(t, "drupal", events[0].InvolvedObject.Name)
+	assert.Equal(t, "drupal-5xx", events[0].Annotations[annotation.KeyCloudWatchAlarmName])
+}
+
+func TestForwardAnnotations(t *testing.T) {
+	f, clientset := newForwarder(t, Options{}, newUnstructured(podGVK, "test", "drupal"))
+
+	alarm := newAlarm()
+	alarm.Annotations = map[string]string{
+		annotation.KeyThreshold:           "10",
+		annotation.KeyCloudWatchAlarmName: "overridden",
+	}
+
+	err := f.Forward(context.TODO(), alarm)
+	assert.NoError(t, err)
+
+	events := listEvents(t, clientset)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "10", events[0].Annotations[annotation.KeyThreshold])
+	assert.Equal(t, "drupal-5xx", events[0].Annotations[annotation.KeyCloudWatchAlarmName])
+}
+
+func TestForwardRollout(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	deployment := newDeployment(t, 2)
+	deployment.SetUID("deployment")
+
+	rs := newUnstructured(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, "test", "drupal-abc")
+	rs.SetCreationTimestamp(metav1.NewTime(now.Add(-10 * time.Minute)))
+	rs.SetAnnotations(map[string]string{rollout.AnnotationRevision: "7"})
+	rs.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "drupal", UID: "deployment"}})
+	assert.NoError(t, unstructured.SetNestedSlice(rs.Object, []any{map[string]any{"name": "drupal", "image": "drupal:1.2"}}, "spec", "template", "spec", "containers"))
+
+	f, clientset := newForwarder(t, Options{
+		Rollouts: rollout.New(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), rs), time.Hour),
+	}, deployment)
+	f.now = func() time.Time { return now }
+
+	alarm := newActionAlarm()
+	alarm.Target.Action = ""
+
+	assert.NoError(t, f.Forward(context.TODO(), alarm))
+
+	events := listEvents(t, clientset)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "This is a test; last rollout 10 minutes ago (revision 7, image drupal:1.2)", events[0].Message)
+	assert.Equal(t, "7", events[0].Annotations[annotation.KeyRolloutRevision])
+	assert.Equal(t, "Deployment/drupal", events[0].Annotations[annotation.KeyRolloutObject])
+
+	// Recovery events are not correlated.
+	alarm.State = severity.StateOK
+	assert.NoError(t, f.Forward(context.TODO(), alarm))
+
+	events = listEvents(t, clientset)
+	assert.Len(t, events, 2)
+
+	for _, event := range events {
+		if event.Type == corev1.EventTypeNormal {
+			assert.Equal(t, "This is a test", event.Message)
+			assert.Empty(t, event.Annotations[annotation.KeyRolloutRevision])
+		}
+	}
+}
+
+func TestForwardReport(t *testing.T) {
+	f, clientset := newForwarder(t, Options{RateLimit: 1}, newUnstructured(podGVK, "test", "drupal"))
+
+	rep := &report.Target{}
+	ctx := report.WithContext(context.TODO(), rep)
+
+	assert.NoError(t, f.Forward(ctx, newAlarm()))
+
+	// The fake clientset does not set a creation timestamp, so the created event is not counted by the rate limit.
+	_, err := clientset.CoreV1().Events("test").Create(context.TODO(), &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "existing",
+			CreationTimestamp: metav1.Now(),
+		},
+		Source: corev1.EventSource{
+			Component: SourceComponent,
+		},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.Forward(ctx, newAlarm()))
+
+	assert.Len(t, rep.Events, 1)
+	assert.Equal(t, report.OperationCreated, rep.Events[0].Operation)
+	assert.Equal(t, "Pod/drupal", rep.Events[0].Object)
+	assert.Equal(t, []report.Decision{{Outcome: report.OutcomeSuppressed, Reason: "rate limited", Object: "Pod/drupal"}}, rep.Decisions)
+}
+
+func TestForwardClusterScoped(t *testing.T) {
+	f, clientset := newForwarder(t, Options{}, newUnstructured(nodeGVK, "", "node-1"), newUnstructured(namespaceGVK, "", metav1.NamespaceDefault))
+
+	alarm := newAlarm()
+	alarm.Target.Kind = "Node"
+	alarm.Target.Name = "node-1"
+
+	err := f.Forward(context.TODO(), alarm)
+	assert.NoError(t, err)
+
+	list, err := clientset.CoreV1().Events(metav1.NamespaceDefault).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, "Node", list.Items[0].InvolvedObject.Kind)
+	assert.Equal(t, "node-1", list.Items[0].InvolvedObject.Name)
+	assert.Empty(t, list.Items[0].InvolvedObject.Namespace)
+	assert.Len(t, listEvents(t, clientset), 0)
+}
+
+func TestForwardRateLimit(t *testing.T) {
+	f, clientset := newForwarder(t, Options{RateLimit: 1}, newUnstructured(podGVK, "test", "drupal"))
+
+	// The fake clientset does not set a creation timestamp.
+	_, err := clientset.CoreV1().Events("test").Create(context.TODO(), &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "existing",
+			CreationTimestamp: metav1.Now(),
+		},
+		Source: corev1.EventSource{
+			Component: SourceComponent,
+		},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	err = f.Forward(context.TODO(), newAlarm())
+	assert.NoError(t, err)
+	assert.Len(t, listEvents(t, clientset), 1)
+}
+
+// suppressedCount flushes the recorder and returns the number of suppressed events.
+func suppressedCount(t *testing.T, recorder *metrics.Recorder, buf *bytes.Buffer) float64 {
+	assert.NoError(t, recorder.Flush())
+
+	var document map[string]any
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		assert.NoError(t, json.Unmarshal([]byte(line), &document))
+
+		if value, ok := document[metrics.EventsSuppressed].(float64); ok {
+			return value
+		}
+	}
+
+	return 0
+}
+
+func TestForwardSuppressionWindow(t *testing.T) {
+	// The window started within the last two minutes, so it is always active.
+	checker, err := suppress.New(`[{"namespace": "te*", "schedule": "* * * * *", "duration": "2m"}]`)
+	assert.NoError(t, err)
+
+	f, clientset := newForwarder(t, Options{Checker: checker}, newUnstructured(podGVK, "test", "drupal"))
+
+	var (
+		buf      bytes.Buffer
+		recorder = metrics.New(&buf)
+		rep      = &report.Target{}
+		ctx      = report.WithContext(metrics.WithContext(context.TODO(), recorder), rep)
+	)
+
+	assert.NoError(t, f.Forward(ctx, newAlarm()))
+	assert.Len(t, listEvents(t, clientset), 0)
+	assert.Equal(t, float64(1), suppressedCount(t, recorder, &buf))
+	assert.Equal(t, []report.Decision{{Outcome: report.OutcomeSuppressed, Reason: `suppression window "* * * * *" for 2m`}}, rep.Decisions)
+
+	// Windows only apply to matching namespaces.
+	checker, err = suppress.New(`[{"namespace": "skpr-*", "schedule": "* * * * *", "duration": "2m"}]`)
+	assert.NoError(t, err)
+
+	f, clientset = newForwarder(t, Options{Checker: checker}, newUnstructured(podGVK, "test", "drupal"))
+
+	assert.NoError(t, f.Forward(context.TODO(), newAlarm()))
+	assert.Len(t, listEvents(t, clientset), 1)
+}
+
+func TestForwardMutedNamespace(t *testing.T) {
+	namespace := newUnstructured(namespaceGVK, "", "test")
+	namespace.SetAnnotations(map[string]string{annotation.KeyMutedAlarms: "other, drupal-5xx"})
+
+	f, clientset := newForwarder(t, Options{}, newUnstructured(podGVK, "test", "drupal"), namespace)
+
+	var (
+		buf      bytes.Buffer
+		recorder = metrics.New(&buf)
+		rep      = &report.Target{}
+		ctx      = report.WithContext(metrics.WithContext(context.TODO(), recorder), rep)
+	)
+
+	assert.NoError(t, f.Forward(ctx, newAlarm()))
+	assert.Len(t, listEvents(t, clientset), 0)
+	assert.Equal(t, float64(1), suppressedCount(t, recorder, &buf))
+	assert.Equal(t, []report.Decision{{Outcome: report.OutcomeSuppressed, Reason: "namespace test muted alarm drupal-5xx"}}, rep.Decisions)
+}
+
+func TestForwardMutedObject(t *testing.T) {
+	pod := newUnstructured(podGVK, "test", "drupal")
+	pod.SetAnnotations(map[string]string{annotation.KeyMutedUntil: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)})
+
+	f, clientset := newForwarder(t, Options{}, pod)
+
+	var (
+		buf      bytes.Buffer
+		recorder = metrics.New(&buf)
+		rep      = &report.Target{}
+		ctx      = report.WithContext(metrics.WithContext(context.TODO(), recorder), rep)
+	)
+
+	assert.NoError(t, f.Forward(ctx, newAlarm()))
+	assert.Len(t, listEvents(t, clientset), 0)
+	assert.Equal(t, float64(1), suppressedCount(t, recorder, &buf))
+
+	if assert.Len(t, rep.Decisions, 1) {
+		assert.Equal(t, report.OutcomeSuppressed, rep.Decisions[0].Outcome)
+		assert.Equal(t, "Pod/drupal", rep.Decisions[0].Object)
+	}
+}
+
+func TestForwardMutedUntilInvalid(t *testing.T) {
+	pod := newUnstructured(podGVK, "test", "drupal")
+	pod.SetAnnotations(map[string]string{annotation.KeyMutedUntil: "tomorrow"})
+
+	f, clientset := newForwarder(t, Options{}, pod)
+
+	var (
+		buf bytes.Buffer
+		rep = &report.Target{}
+		ctx = report.WithContext(logging.WithContext(context.TODO(), logging.New(&buf)), rep)
+	)
+
+	// The invalid annotation does not mute the alarm, and is logged so it can be fixed.
+	assert.NoError(t, f.Forward(ctx, newAlarm()))
+	assert.Len(t, listEvents(t, clientset), 1)
+	assert.Empty(t, rep.Decisions)
+	assert.Contains(t, buf.String(), "Ignoring invalid muted until annotation")
+	assert.Contains(t, buf.String(), "tomorrow")
+}
+
+func TestForwardDenied(t *testing.T) {
+	checker, err := allow.New(`{"*": {"namespaces": ["skpr-*"]}}`)
+	assert.NoError(t, err)
+
+	f, clientset := newForwarder(t, Options{Allow: checker}, newUnstructured(podGVK, "test", "drupal"))
+
+	err = f.Forward(context.TODO(), newAlarm())
+	assert.NoError(t, err)
+	assert.Len(t, listEvents(t, clientset), 0)
+
+	checker, err = allow.New(`{"*": {"kinds": ["apps/*"]}}`)
+	assert.NoError(t, err)
+
+	f, clientset = newForwarder(t, Options{Allow: checker}, newUnstructured(podGVK, "test", "drupal"))
+
+	err = f.Forward(context.TODO(), newAlarm())
+	assert.NoError(t, err)
+	assert.Len(t, listEvents(t, clientset), 0)
+}
+
+// createWarning creates an existing Warning event for an alarm.
+func createWarning(t *testing.T, clientset *fake.Clientset, name, alarm string) {
+	_, err := clientset.CoreV1().Events("test").Create(context.TODO(), &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				annotation.KeyCloudWatchAlarmName: alarm,
+			},
+		},
+		Type: corev1.EventTypeWarning,
+		Source: corev1.EventSource{
+			Component: SourceComponent,
+		},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+}
+
+func newRecovery() Alarm {
+	alarm := newAlarm()
+	alarm.State = severity.StateOK
+	return alarm
+}
+
+func TestForwardResolveEvent(t *testing.T) {
+	f, clientset := newForwarder(t, Options{}, newUnstructured(podGVK, "test", "drupal"))
+
+	createWarning(t, clientset, "incident", "drupal-5xx")
+
+	assert.NoError(t, f.Forward(context.TODO(), newRecovery()))
+
+	events := listEvents(t, clientset)
+	assert.Len(t, events, 2)
+
+	for _, event := range events {
+		assert.NotContains(t, event.Annotations, annotation.KeyResolvedAt)
+
+		if event.Name != "incident" {
+			assert.Equal(t, corev1.EventTypeNormal, event.Type)
+		}
+	}
+}
+
+func TestForwardResolveDelete(t *testing.T) {
+	f, clientset := newForwarder(t, Options{ResolveAction: ResolveDelete}, newUnstructured(podGVK, "test", "drupal"))
+
+	createWarning(t, clientset, "incident", "drupal-5xx")
+	createWarning(t, clientset, "other", "other-alarm")
+
+	assert.NoError(t, f.Forward(context.TODO(), newRecovery()))
+
+	var names []string
+
+	for _, event := range listEvents(t, clientset) {
+		names = append(names, event.Name)
+	}
+
+	assert.Len(t, names, 2)
+	assert.Contains(t, names, "other")
+	assert.NotContains(t, names, "incident")
+}
+
+func TestForwardResolveAnnotate(t *testing.T) {
+	f, clientset := newForwarder(t, Options{ResolveAction: ResolveAnnotate}, newUnstructured(podGVK, "test", "drupal"))
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	f.now = func() time.Time {
+		return now
+	}
+
+	createWarning(t, clientset, "incident", "drupal-5xx")
+
+	assert.NoError(t, f.Forward(context.TODO(), newRecovery()))
+
+	event, err := clientset.CoreV1().Events("test").Get(context.TODO(), "incident", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-01T12:00:00Z", event.Annotations[annotation.KeyResolvedAt])
+	assert.Len(t, listEvents(t, clientset), 2)
+}
+
+func newDeployment(t *testing.T, replicas int64) *unstructured.Unstructured {
+	deployment := newUnstructured(deploymentGVK, "test", "drupal")
+	assert.NoError(t, unstructured.SetNestedField(deployment.Object, replicas, "spec", "replicas"))
+	return deployment
+}
+
+func newActionAlarm() Alarm {
+	alarm := newAlarm()
+	alarm.Target.APIGroup = "apps"
+	alarm.Target.APIVersion = "v1"
+	alarm.Target.Kind = "Deployment"
+	alarm.Target.Action = "scale:+2"
+	return alarm
+}
+
+func getReplicas(t *testing.T, f *Forwarder) int64 {
+	objects, err := f.resolver.Resolve(context.TODO(), newActionAlarm().Target)
+	assert.NoError(t, err)
+
+	replicas, _, err := unstructured.NestedInt64(objects[0].Object, "spec", "replicas")
+	assert.NoError(t, err)
+
+	return replicas
+}
+
+func findEvent(events []corev1.Event, reason string) *corev1.Event {
+	for i := range events {
+		if events[i].Reason == reason {
+			return &events[i]
+		}
+	}
+
+	return nil
+}
+
+func TestForwardAction(t *testing.T) {
+	f, clientset := newForwarder(t, Options{
+		Actions: action.Options{
+			Rules: []action.Rule{{Kinds: []string{"apps/Deployment"}, Actions: []string{action.TypeScale}}},
+		},
+	}, newDeployment(t, 1))
+
+	assert.NoError(t, f.Forward(context.TODO(), newActionAlarm()))
+	assert.Equal(t, int64(3), getReplicas(t, f))
+
+	audit := findEvent(listEvents(t, clientset), ReasonAction)
+	assert.NotNil(t, audit)
+	assert.Equal(t, "scale:+2", audit.Annotations[annotation.KeyAction])
+
+	// The second alarm is within the cooldown.
+	assert.NoError(t, f.Forward(context.TODO(), newActionAlarm()))
+	assert.Equal(t, int64(3), getReplicas(t, f))
+}
+
+func TestForwardActionDryRun(t *testing.T) {
+	f, clientset := newForwarder(t, Options{
+		Actions: action.Options{
+			Rules:  []action.Rule{{Actions: []string{action.TypeScale}}},
+			DryRun: true,
+		},
+	}, newDeployment(t, 1))
+
+	assert.NoError(t, f.Forward(context.TODO(), newActionAlarm()))
+	assert.Equal(t, int64(1), getReplicas(t, f))
+	assert.NotNil(t, findEvent(listEvents(t, clientset), ReasonActionDryRun))
+
+	// Dry runs are cooled down like the action would have been.
+	rep := &report.Target{}
+
+	assert.NoError(t, f.Forward(report.WithContext(context.TODO(), rep), newActionAlarm()))
+	assert.Equal(t, int64(1), getReplicas(t, f))
+	assert.Len(t, listEvents(t, clientset), 3)
+
+	if assert.Len(t, rep.Actions, 1) {
+		assert.Equal(t, "cooldown", rep.Actions[0].Skipped)
+	}
+}
+
+func TestForwardActionStale(t *testing.T) {
+	f, clientset := newForwarder(t, Options{
+		Actions: action.Options{
+			Rules: []action.Rule{{Actions: []string{action.TypeScale}}},
+		},
+		StalenessLimit: time.Hour,
+	}, newDeployment(t, 1))
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	f.now = func() time.Time {
+		return now
+	}
+
+	var (
+		buf bytes.Buffer
+		rep = &report.Target{}
+		ctx = report.WithContext(logging.WithContext(context.TODO(), logging.New(&buf)), rep)
+	)
+
+	alarm := newActionAlarm()
+	alarm.Timestamp = now.Add(-2 * time.Hour)
+
+	// The stale event is still marked and created, but the replayed alarm does not scale the workload.
+	assert.NoError(t, f.Forward(ctx, alarm))
+	assert.Equal(t, int64(1), getReplicas(t, f))
+	assert.Len(t, listEvents(t, clientset), 1)
+	assert.Contains(t, buf.String(), "Skipping action because the alarm is stale")
+
+	if assert.Len(t, rep.Actions, 1) {
+		assert.Equal(t, "stale", rep.Actions[0].Skipped)
+	}
+}
+
+func TestForwardActionDenied(t *testing.T) {
+	f, clientset := newForwarder(t, Options{
+		Actions: action.Options{
+			Rules: []action.Rule{{Actions: []string{action.TypeRolloutRestart}}},
+		},
+	}, newDeployment(t, 1))
+
+	assert.NoError(t, f.Forward(context.TODO(), newActionAlarm()))
+	assert.Equal(t, int64(1), getReplicas(t, f))
+
+	events := listEvents(t, clientset)
+	assert.Len(t, events, 1)
+	assert.Nil(t, findEvent(events, ReasonAction))
+}
+
+func TestForwardActionFailed(t *testing.T) {
+	f, clientset := newForwarder(t, Options{
+		Actions: action.Options{
+			Rules: []action.Rule{{Actions: []string{action.TypeScale}}},
+		},
+	}, newUnstructured(podGVK, "test", "drupal"))
+
+	rep := &report.Target{}
+
+	alarm := newAlarm()
+	alarm.Target.Action = "scale:+2"
+
+	// Pods cannot be scaled, which is reported without failing the alarm.
+	assert.NoError(t, f.Forward(report.WithContext(context.TODO(), rep), alarm))
+	assert.Len(t, listEvents(t, clientset), 1)
+
+	if assert.Len(t, rep.Actions, 1) {
+		assert.Contains(t, rep.Actions[0].Error, "does not have replicas")
+	}
+
+	// Actions which cannot be parsed fail before any events are created.
+	alarm.Target.Action = "explode"
+	assert.ErrorContains(t, f.Forward(context.TODO(), alarm), "failed to parse action")
+	assert.Len(t, listEvents(t, clientset), 1)
+}
+
+func TestForwardFlapping(t *testing.T) {
+	f, clientset := newForwarder(t, Options{
+		Detector: flap.Detector{
+			Threshold: 2,
+			Window:    time.Hour,
+		},
+	}, newUnstructured(podGVK, "test", "drupal"))
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, f.Forward(context.TODO(), newAlarm()))
+	}
+
+	events := listEvents(t, clientset)
+	assert.Len(t, events, 2)
+
+	var reasons []string
+
+	for _, event := range events {
+		reasons = append(reasons, event.Reason)
+	}
+
+	assert.ElementsMatch(t, []string{"HighErrorRate", ReasonFlapping}, reasons)
+}
+
+func TestForwardFlappingResolve(t *testing.T) {
+	f, clientset := newForwarder(t, Options{
+		Detector: flap.Detector{
+			Threshold: 2,
+			Window:    time.Hour,
+		},
+		ResolveAction: ResolveDelete,
+	}, newUnstructured(podGVK, "test", "drupal"))
+
+	assert.NoError(t, f.Forward(context.TODO(), newAlarm()))
+	assert.NoError(t, f.Forward(context.TODO(), newRecovery()))
+	assert.NoError(t, f.Forward(context.TODO(), newAlarm()))
+
+	createWarning(t, clientset, "incident", "drupal-5xx")
+
+	// The recovery is held because the alarm is flapping, but its Warning events are still resolved.
+	assert.NoError(t, f.Forward(context.TODO(), newRecovery()))
+
+	for _, event := range listEvents(t, clientset) {
+		assert.NotEqual(t, "incident", event.Name)
+	}
+}
+
+func TestForwardFlappingRetry(t *testing.T) {
+	f, clientset := newForwarder(t, Options{
+		Detector: flap.Detector{
+			Threshold: 2,
+			Window:    time.Hour,
+		},
+	}, newUnstructured(podGVK, "test", "drupal"))
+
+	failures := 2
+
+	clientset.PrependReactor("create", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, fmt.Errorf("etcdserver: request timed out")
+		}
+
+		return false, nil, nil
+	})
+
+	alarm := newAlarm()
+	alarm.Timestamp = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// The transition is stored before the event fails, so the retries must not count it again.
+	assert.Error(t, f.Forward(context.TODO(), alarm))
+	assert.Error(t, f.Forward(context.TODO(), alarm))
+	assert.NoError(t, f.Forward(context.TODO(), alarm))
+
+	events := listEvents(t, clientset)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "HighErrorRate", events[0].Reason)
+	}
+}
+
+func TestForwardTimestamps(t *testing.T) {
+	f, clientset := newForwarder(t, Options{
+		StalenessLimit: time.Hour,
+	}, newUnstructured(podGVK, "test", "drupal"))
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	f.now = func() time.Time {
+		return now
+	}
+
+	alarm := newAlarm()
+	alarm.State = severity.StateOK
+	alarm.Timestamp = now.Add(-2 * time.Hour)
+	alarm.PreviousTimestamp = now.Add(-3 * time.Hour)
+
+	assert.NoError(t, f.Forward(context.TODO(), alarm))
+
+	events := listEvents(t, clientset)
+	assert.Len(t, events, 1)
+	assert.Equal(t, alarm.PreviousTimestamp, events[0].FirstTimestamp.Time.UTC())
+	assert.Equal(t, alarm.Timestamp, events[0].LastTimestamp.Time.UTC())
+	assert.Equal(t, "2h0m0s", events[0].Annotations[annotation.KeyDeliveryLag])
+	assert.Equal(t, "true", events[0].Annotations[annotation.KeyStale])
+
+	f.options.StalenessAction = StaleDrop
+
+	assert.NoError(t, f.Forward(context.TODO(), alarm))
+	assert.Len(t, listEvents(t, clientset), 1)
+}
@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/smithy-go"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Namespace which metrics are published to.
+const Namespace = "Skpr/LambdaEKSEventCloudWatch"
+
+const (
+	// EventsCreated is the number of events created.
+	EventsCreated = "EventsCreated"
+	// EventsDeduplicated is the number of events held because the alarm is flapping.
+	EventsDeduplicated = "EventsDeduplicated"
+	// EventsSuppressed is the number of events suppressed by mutes, windows, rate limits or staleness.
+	EventsSuppressed = "EventsSuppressed"
+	// EventsResolved is the number of Warning events deleted or annotated when an alarm returned to OK.
+	EventsResolved = "EventsResolved"
+	// EventsDenied is the number of events denied by the allowlist.
+	EventsDenied = "EventsDenied"
+	// ActionsExecuted is the number of remediation actions executed, including dry runs.
+	ActionsExecuted = "ActionsExecuted"
+	// ActionsSkipped is the number of remediation actions skipped because they were not permitted or cooling down.
+	ActionsSkipped = "ActionsSkipped"
+	// ActionsFailed is the number of remediation actions which could not be planned or executed.
+	ActionsFailed = "ActionsFailed"
+	// SinkFailed is the number of notification sinks which failed.
+	SinkFailed = "SinkFailed"
+	// EventsFailed is the number of invocations which failed, by error class.
+	EventsFailed = "EventsFailed"
+	// DeliveryLag is the time between the alarm changing state and the event being created.
+	DeliveryLag = "DeliveryLag"
+)
+
+const (
+	// StageTags is the latency of looking up tags.
+	StageTags = "Tags"
+	// StageDescribeCluster is the latency of describing the EKS cluster.
+	StageDescribeCluster = "DescribeCluster"
+	// StageToken is the latency of generating the EKS token.
+	StageToken = "Token"
+	// StageObjectLookup is the latency of resolving the target objects.
+	StageObjectLookup = "ObjectLookup"
+	// StageCreate is the latency of creating events.
+	StageCreate = "Create"
+)
+
+// DimensionErrorClass is the dimension used for failures.
+const DimensionErrorClass = "ErrorClass"
+
+// DimensionCluster is the dimension used for counts and latencies recorded while processing a cluster.
+const DimensionCluster = "Cluster"
+
+// Recorder collects metrics for a single invocation and writes them in CloudWatch Embedded Metric Format.
+type Recorder struct {
+	mu         sync.Mutex
+	writer     io.Writer
+	dimensions map[string]string
+	counts     map[string]float64
+	latencies  map[string][]float64
+	failures   map[string]float64
+	now        func() time.Time
+}
+
+// New creates a recorder which writes to the given writer when flushed.
+func New(w io.Writer) *Recorder {
+	return &Recorder{
+		writer:     w,
+		dimensions: make(map[string]string),
+		counts:     make(map[string]float64),
+		latencies:  make(map[string][]float64),
+		failures:   make(map[string]float64),
+		now:        time.Now,
+	}
+}
+
+// SetDimension which will be applied to counts and latencies.
+func (r *Recorder) SetDimension(key, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dimensions[key] = value
+}
+
+// Add to a count.
+func (r *Recorder) Add(name string, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counts[name] += float64(n)
+}
+
+// Duration records a latency in milliseconds.
+func (r *Recorder) Duration(name string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies[name] = append(r.latencies[name], float64(d.Milliseconds()))
+}
+
+// Stage starts timing a stage and returns a function which records the latency when called.
+func (r *Recorder) Stage(name string) func() {
+	start := r.now()
+
+	return func() {
+		r.Duration(name+"Latency", r.now().Sub(start))
+	}
+}
+
+// Failed records a failure classified by error.
+func (r *Recorder) Failed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures[Classify(err)]++
+}
+
+// Flush writes the metrics in Embedded Metric Format.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		timestamp  = r.now().UnixMilli()
+		document   = make(map[string]any)
+		dimensions = make([]string, 0, len(r.dimensions))
+		metrics    []map[string]string
+	)
+
+	for key, value := range r.dimensions {
+		dimensions = append(dimensions, key)
+		document[key] = value
+	}
+
+	sort.Strings(dimensions)
+
+	for name, value := range r.counts {
+		document[name] = value
+		metrics = append(metrics, map[string]string{"Name": name, "Unit": "Count"})
+	}
+
+	for name, values := range r.latencies {
+		document[name] = values
+		metrics = append(metrics, map[string]string{"Name": name, "Unit": "Milliseconds"})
+	}
+
+	if len(metrics) > 0 {
+		if err := r.write(document, timestamp, dimensions, metrics); err != nil {
+			return err
+		}
+	}
+
+	for class, value := range r.failures {
+		err := r.write(map[string]any{
+			DimensionErrorClass: class,
+			EventsFailed:        value,
+		}, timestamp, []string{DimensionErrorClass}, []map[string]string{{"Name": EventsFailed, "Unit": "Count"}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write a single Embedded Metric Format document.
+func (r *Recorder) write(document map[string]any, timestamp int64, dimensions []string, metrics []map[string]string) error {
+	document["_aws"] = map[string]any{
+		"Timestamp": timestamp,
+		"CloudWatchMetrics": []map[string]any{
+			{
+				"Namespace":  Namespace,
+				"Dimensions": [][]string{dimensions},
+				"Metrics":    metrics,
+			},
+		},
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+
+	_, err = fmt.Fprintln(r.writer, string(data))
+
+	return err
+}
+
+// Classify an error for reporting.
+func Classify(err error) string {
+	var apiErr smithy.APIError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "Timeout"
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return "KubernetesAuth"
+	case apierrors.IsNotFound(err):
+		return "KubernetesNotFound"
+	case apierrors.ReasonForError(err) != "":
+		return "Kubernetes"
+	case errors.As(err, &apiErr):
+		return "AWS"
+	}
+
+	return "Internal"
+}
+
+type contextKey struct{}
+
+// WithContext returns a copy of the context which carries the recorder.
+func WithContext(ctx context.Context, recorder *Recorder) context.Context {
+	return context.WithValue(ctx, contextKey{}, recorder)
+}
+
+// FromContext returns the recorder carried by the context, or a recorder which discards metrics.
+func FromContext(ctx context.Context) *Recorder {
+	if recorder, ok := ctx.Value(contextKey{}).(*Recorder); ok {
+		return recorder
+	}
+
+	return New(io.Discard)
+}
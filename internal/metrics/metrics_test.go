@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestFlush(t *testing.T) {
+	var buf bytes.Buffer
+
+	recorder := New(&buf)
+	recorder.now = func() time.Time {
+		return time.UnixMilli(1704067200000)
+	}
+
+	recorder.SetDimension("Cluster", "skpr-test")
+	recorder.Add(EventsCreated, 2)
+	recorder.Duration(StageCreate+"Latency", 150*time.Millisecond)
+	recorder.Failed(context.DeadlineExceeded)
+
+	assert.NoError(t, recorder.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var document map[string]any
+
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &document))
+	assert.Equal(t, "skpr-test", document["Cluster"])
+	assert.Equal(t, float64(2), document[EventsCreated])
+	assert.Equal(t, []any{float64(150)}, document["CreateLatency"])
+
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &document))
+	assert.Equal(t, "Timeout", document[DimensionErrorClass])
+	assert.Equal(t, float64(1), document[EventsFailed])
+}
+
+func TestClassify(t *testing.T) {
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "events"}, "foo", fmt.Errorf("denied"))
+
+	assert.Equal(t, "KubernetesAuth", Classify(fmt.Errorf("failed to create event: %w", forbidden)))
+	assert.Equal(t, "Timeout", Classify(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)))
+	assert.Equal(t, "Internal", Classify(fmt.Errorf("something else")))
+}
@@ -0,0 +1,408 @@
+package permissions
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// MappingAWSAuth maps the IAM role to a Kubernetes group using the aws-auth ConfigMap.
+	MappingAWSAuth = "aws-auth"
+	// MappingAccessEntry maps the IAM role to a Kubernetes group using an EKS access entry.
+	MappingAccessEntry = "access-entry"
+)
+
+// Resource which the forwarder needs access to.
+type Resource struct {
+	APIGroup string
+	Resource string
+}
+
+// clusterScoped are the built-in resources which are not namespaced.
+var clusterScoped = []Resource{
+	{Resource: "nodes"},
+	{Resource: "namespaces"},
+	{Resource: "persistentvolumes"},
+	{APIGroup: "storage.k8s.io", Resource: "storageclasses"},
+	{APIGroup: "apiextensions.k8s.io", Resource: "customresourcedefinitions"},
+}
+
+// ParseResources from a comma separated list eg. "workflow.skpr.io/environments,pods".
+func ParseResources(value string) ([]Resource, error) {
+	var resources []Resource
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		group, resource, found := strings.Cut(item, "/")
+		if !found {
+			group, resource = "", group
+		}
+
+		if resource == "" {
+			return nil, fmt.Errorf("invalid resource: %s", item)
+		}
+
+		resources = append(resources, Resource{APIGroup: group, Resource: resource})
+	}
+
+	return resources, nil
+}
+
+// Features which determine the permissions required.
+type Features struct {
+	// Name used for the ClusterRole, bindings and Kubernetes group.
+	Name string
+	// Cluster which the forwarder connects to. All clusters are allowed if empty.
+	Cluster string
+	// Namespaces which events are created in. All namespaces are allowed if empty.
+	Namespaces []string
+	// ClusterNamespace is the namespace which events for cluster-scoped objects are created in.
+	ClusterNamespace string
+	// ClusterScoped are custom resources which are not namespaced, in addition to built-in ones such as nodes.
+	ClusterScoped []Resource
+	// Targets are the resources which events are associated with.
+	Targets []Resource
+	// Owners are the resources which owner references are walked to.
+	Owners []Resource
+	// Logs subscription payloads are routed using log group tags.
+	Logs bool
+	// Services events are routed using tags looked up with the resource groups tagging API.
+	Services bool
+	// RulesFile is loaded from S3 when it is an s3:// URI.
+	RulesFile string
+	// Selector targets are listed rather than fetched by name.
+	Selector bool
+	// Flapping state is stored as an annotation on the target.
+	Flapping bool
+	// RateLimit counts existing events in the namespace.
+	RateLimit bool
+	// Actions patch targets to execute remediation actions.
+	Actions bool
+	// Rollouts list workloads and their revisions to correlate alarms with the last rollout.
+	Rollouts bool
+	// ResolveVerb is used on existing events when the alarm returns to OK eg. "delete" or "patch".
+	ResolveVerb string
+}
+
+// Statement in an IAM policy.
+type Statement struct {
+	Action   []string `json:"Action"`
+	Effect   string   `json:"Effect"`
+	Resource []string `json:"Resource"`
+}
+
+// Policy is an IAM policy document.
+type Policy struct {
+	Statement []Statement `json:"Statement"`
+	Version   string      `json:"Version"`
+}
+
+// IAMPolicy returns the minimal IAM policy for the features.
+func IAMPolicy(f Features) (Policy, error) {
+	cluster := "*"
+	if f.Cluster != "" {
+		cluster = f.Cluster
+	}
+
+	policy := Policy{
+		Version: "2012-10-17",
+		Statement: []Statement{
+			{
+				Action:   []string{"cloudwatch:ListTagsForResource"},
+				Effect:   "Allow",
+				Resource: []string{"*"},
+			},
+			{
+				Action:   []string{"eks:DescribeCluster"},
+				Effect:   "Allow",
+				Resource: []string{fmt.Sprintf("arn:aws:eks:*:*:cluster/%s", cluster)},
+			},
+		},
+	}
+
+	if f.Logs {
+		policy.Statement = append(policy.Statement, Statement{
+			Action:   []string{"logs:ListTagsForResource"},
+			Effect:   "Allow",
+			Resource: []string{"*"},
+		})
+	}
+
+	if f.Services {
+		policy.Statement = append(policy.Statement, Statement{
+			Action:   []string{"tag:GetResources"},
+			Effect:   "Allow",
+			Resource: []string{"*"},
+		})
+	}
+
+	if strings.HasPrefix(f.RulesFile, "s3://") {
+		u, err := url.Parse(f.RulesFile)
+		if err != nil {
+			return Policy{}, fmt.Errorf("failed to parse s3 uri: %w", err)
+		}
+
+		policy.Statement = append(policy.Statement, Statement{
+			Action:   []string{"s3:GetObject"},
+			Effect:   "Allow",
+			Resource: []string{fmt.Sprintf("arn:aws:s3:::%s%s", u.Host, u.Path)},
+		})
+	}
+
+	return policy, nil
+}
+
+// Rules returns the namespaced RBAC rules for the features.
+func Rules(f Features) []rbacv1.PolicyRule {
+	verbs := make(map[Resource]map[string]bool)
+
+	add := func(resource Resource, verb ...string) {
+		if verbs[resource] == nil {
+			verbs[resource] = make(map[string]bool)
+		}
+
+		for _, v := range verb {
+			verbs[resource][v] = true
+		}
+	}
+
+	events := Resource{Resource: "events"}
+
+	add(events, "create")
+
+	if f.RateLimit {
+		add(events, "list")
+	}
+
+	if f.ResolveVerb != "" {
+		add(events, "list", f.ResolveVerb)
+	}
+
+	for _, target := range f.Targets {
+		add(target, "get")
+
+		if f.Selector {
+			add(target, "list")
+		}
+
+		if f.Flapping || f.Actions {
+			add(target, "patch")
+		}
+	}
+
+	// Flapping state and remediation actions are written to the top-level owner when owners are walked.
+	for _, owner := range f.Owners {
+		add(owner, "get")
+
+		if f.Flapping || f.Actions {
+			add(owner, "patch")
+		}
+	}
+
+	if f.Rollouts {
+		for _, resource := range []string{"deployments", "statefulsets", "replicasets", "controllerrevisions"} {
+			add(Resource{APIGroup: "apps", Resource: resource}, "list")
+		}
+	}
+
+	var rules []rbacv1.PolicyRule
+
+	for resource, set := range verbs {
+		rule := rbacv1.PolicyRule{
+			APIGroups: []string{resource.APIGroup},
+			Resources: []string{resource.Resource},
+		}
+
+		for verb := range set {
+			rule.Verbs = append(rule.Verbs, verb)
+		}
+
+		sort.Strings(rule.Verbs)
+
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].APIGroups[0] != rules[j].APIGroups[0] {
+			return rules[i].APIGroups[0] < rules[j].APIGroups[0]
+		}
+
+		return rules[i].Resources[0] < rules[j].Resources[0]
+	})
+
+	return rules
+}
+
+// scoped returns whether the resource is cluster-scoped.
+func (f Features) scoped(resource Resource) bool {
+	return slices.Contains(clusterScoped, resource) || slices.Contains(f.ClusterScoped, resource)
+}
+
+// hasClusterScoped returns whether any targets or owners are cluster-scoped.
+func (f Features) hasClusterScoped() bool {
+	for _, resource := range append(slices.Clone(f.Targets), f.Owners...) {
+		if f.scoped(resource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clusterRules returns the cluster scoped RBAC rules which are always required.
+func clusterRules() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"namespaces"},
+			Verbs:     []string{"get"},
+		},
+	}
+}
+
+// RBAC returns the ClusterRole, Roles and bindings for the features. Namespaced rules are granted in each namespace
+// using a Role when namespaces are provided, otherwise they are added to the ClusterRole. Rules for cluster-scoped
+// resources are always added to the ClusterRole, since a Role can't grant them.
+func RBAC(f Features) ([]any, error) {
+	var (
+		subjects = []rbacv1.Subject{
+			{
+				Kind:     rbacv1.GroupKind,
+				APIGroup: rbacv1.GroupName,
+				Name:     f.Name,
+			},
+		}
+		clusterRole = &rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: f.Name},
+			Rules:      clusterRules(),
+		}
+		namespaced []rbacv1.PolicyRule
+	)
+
+	for _, rule := range Rules(f) {
+		if len(f.Namespaces) == 0 || f.scoped(Resource{APIGroup: rule.APIGroups[0], Resource: rule.Resources[0]}) {
+			clusterRole.Rules = append(clusterRole.Rules, rule)
+		} else {
+			namespaced = append(namespaced, rule)
+		}
+	}
+
+	// Events for cluster-scoped objects are created in the cluster namespace, so it must be granted a Role.
+	if len(f.Namespaces) > 0 && f.hasClusterScoped() {
+		namespace := f.ClusterNamespace
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+
+		if !slices.Contains(f.Namespaces, namespace) {
+			return nil, fmt.Errorf("cluster scoped namespace %s must be one of the namespaces", namespace)
+		}
+	}
+
+	objects := []any{
+		clusterRole,
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: f.Name},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     f.Name,
+			},
+			Subjects: subjects,
+		},
+	}
+
+	for _, namespace := range f.Namespaces {
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: f.Name, Namespace: namespace},
+				Rules:      namespaced,
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: f.Name, Namespace: namespace},
+				RoleRef: rbacv1.RoleRef{
+					APIGroup: rbacv1.GroupName,
+					Kind:     "Role",
+					Name:     f.Name,
+				},
+				Subjects: subjects,
+			},
+		)
+	}
+
+	return objects, nil
+}
+
+// AccessEntry is the input for "aws eks create-access-entry --cli-input-json".
+type AccessEntry struct {
+	ClusterName      string   `json:"clusterName"`
+	PrincipalARN     string   `json:"principalArn"`
+	KubernetesGroups []string `json:"kubernetesGroups"`
+	Type             string   `json:"type"`
+}
+
+// RoleMapping is an entry for the mapRoles list in the aws-auth ConfigMap.
+type RoleMapping struct {
+	RoleARN  string   `json:"rolearn"`
+	Username string   `json:"username"`
+	Groups   []string `json:"groups"`
+}
+
+// Mapping returns the object which maps the IAM role to the Kubernetes group. For aws-auth this is only the mapRoles
+// entry, which is merged into the existing list, since replacing the ConfigMap would remove the node role mappings.
+func Mapping(f Features, mode, roleARN string) (any, error) {
+	switch mode {
+	case MappingAccessEntry, "":
+		if f.Cluster == "" {
+			return nil, fmt.Errorf("cluster is required for access entries")
+		}
+
+		return AccessEntry{
+			ClusterName:      f.Cluster,
+			PrincipalARN:     roleARN,
+			KubernetesGroups: []string{f.Name},
+			Type:             "STANDARD",
+		}, nil
+	case MappingAWSAuth:
+		return []RoleMapping{
+			{
+				RoleARN:  roleARN,
+				Username: f.Name,
+				Groups:   []string{f.Name},
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown mapping: %s", mode)
+}
+
+// Marshal objects as a multi-document YAML stream.
+func Marshal(objects ...any) ([]byte, error) {
+	var documents []string
+
+	for _, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal object: %w", err)
+		}
+
+		documents = append(documents, string(data))
+	}
+
+	return []byte(strings.Join(documents, "---\n")), nil
+}
@@ -0,0 +1,147 @@
+package permissions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestParseResources(t *testing.T) {
+	resources, err := ParseResources("workflow.skpr.io/environments, pods")
+	assert.NoError(t, err)
+	assert.Equal(t, []Resource{
+		{APIGroup: "workflow.skpr.io", Resource: "environments"},
+		{Resource: "pods"},
+	}, resources)
+
+	_, err = ParseResources("apps/")
+	assert.Error(t, err)
+}
+
+func TestIAMPolicy(t *testing.T) {
+	policy, err := IAMPolicy(Features{Cluster: "test"})
+	assert.NoError(t, err)
+	assert.Len(t, policy.Statement, 2)
+	assert.Equal(t, []string{"arn:aws:eks:*:*:cluster/test"}, policy.Statement[1].Resource)
+
+	policy, err = IAMPolicy(Features{RulesFile: "s3://bucket/rules.yaml", Logs: true, Services: true})
+	assert.NoError(t, err)
+	assert.Len(t, policy.Statement, 5)
+	assert.Equal(t, []string{"logs:ListTagsForResource"}, policy.Statement[2].Action)
+	assert.Equal(t, []string{"tag:GetResources"}, policy.Statement[3].Action)
+	assert.Equal(t, []string{"arn:aws:s3:::bucket/rules.yaml"}, policy.Statement[4].Resource)
+}
+
+func TestRules(t *testing.T) {
+	environments := Resource{APIGroup: "workflow.skpr.io", Resource: "environments"}
+
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+		{APIGroups: []string{"workflow.skpr.io"}, Resources: []string{"environments"}, Verbs: []string{"get"}},
+	}, Rules(Features{Targets: []Resource{environments}}))
+
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create", "list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "patch"}},
+		{APIGroups: []string{"workflow.skpr.io"}, Resources: []string{"environments"}, Verbs: []string{"get", "list", "patch"}},
+	}, Rules(Features{
+		Targets:   []Resource{environments},
+		Owners:    []Resource{{APIGroup: "apps", Resource: "deployments"}},
+		Selector:  true,
+		Flapping:  true,
+		RateLimit: true,
+	}))
+
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create", "delete", "list"}},
+	}, Rules(Features{ResolveVerb: "delete"}))
+
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "patch"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "patch"}},
+	}, Rules(Features{
+		Targets: []Resource{{Resource: "pods"}},
+		Owners:  []Resource{{APIGroup: "apps", Resource: "deployments"}},
+		Actions: true,
+	}))
+
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"controllerrevisions"}, Verbs: []string{"list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"replicasets"}, Verbs: []string{"list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"statefulsets"}, Verbs: []string{"list"}},
+	}, Rules(Features{
+		Targets:  []Resource{{APIGroup: "apps", Resource: "deployments"}},
+		Rollouts: true,
+	}))
+}
+
+func TestRBAC(t *testing.T) {
+	objects, err := RBAC(Features{Name: "test", Targets: []Resource{{Resource: "pods"}}})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Len(t, objects[0].(*rbacv1.ClusterRole).Rules, 3)
+
+	objects, err = RBAC(Features{Name: "test", Namespaces: []string{"a", "b"}, Targets: []Resource{{Resource: "pods"}}})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 6)
+	assert.Len(t, objects[0].(*rbacv1.ClusterRole).Rules, 1)
+	assert.Equal(t, "b", objects[4].(*rbacv1.Role).Namespace)
+}
+
+func TestRBACClusterScoped(t *testing.T) {
+	clusters := Resource{APIGroup: "example.com", Resource: "clusters"}
+
+	features := Features{
+		Name:          "test",
+		Namespaces:    []string{"default", "b"},
+		Targets:       []Resource{{Resource: "pods"}, {Resource: "nodes"}},
+		Owners:        []Resource{clusters},
+		ClusterScoped: []Resource{clusters},
+	}
+
+	objects, err := RBAC(features)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 6)
+
+	// Cluster-scoped resources can only be granted by the ClusterRole.
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get"}},
+		{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"get"}},
+		{APIGroups: []string{"example.com"}, Resources: []string{"clusters"}, Verbs: []string{"get"}},
+	}, objects[0].(*rbacv1.ClusterRole).Rules)
+
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+	}, objects[2].(*rbacv1.Role).Rules)
+
+	// Events for cluster-scoped objects must be created in one of the namespaces.
+	features.ClusterNamespace = "cluster-events"
+
+	_, err = RBAC(features)
+	assert.ErrorContains(t, err, "cluster scoped namespace cluster-events must be one of the namespaces")
+}
+
+func TestMapping(t *testing.T) {
+	mapping, err := Mapping(Features{Name: "test", Cluster: "cluster"}, MappingAWSAuth, "arn:aws:iam::123456789012:role/test")
+	assert.NoError(t, err)
+
+	// Only the mapRoles entry is output so the existing mappings are not replaced.
+	data, err := Marshal(mapping)
+	assert.NoError(t, err)
+	assert.Equal(t, "- groups:\n  - test\n  rolearn: arn:aws:iam::123456789012:role/test\n  username: test\n", string(data))
+
+	mapping, err = Mapping(Features{Name: "test", Cluster: "cluster"}, "", "arn:aws:iam::123456789012:role/test")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test"}, mapping.(AccessEntry).KubernetesGroups)
+
+	_, err = Mapping(Features{Name: "test"}, MappingAccessEntry, "arn:aws:iam::123456789012:role/test")
+	assert.ErrorContains(t, err, "cluster is required")
+
+	_, err = Mapping(Features{}, "unknown", "")
+	assert.Error(t, err)
+}
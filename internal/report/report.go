@@ -0,0 +1,140 @@
+package report
+
+import (
+	"context"
+	"sync"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/metrics"
+)
+
+const (
+	// OperationCreated is recorded when an event is created.
+	OperationCreated = "created"
+	// OperationDeleted is recorded when an event is deleted because the alarm returned to OK.
+	OperationDeleted = "deleted"
+	// OperationAnnotated is recorded when an event is annotated because the alarm returned to OK.
+	OperationAnnotated = "annotated"
+
+	// OutcomeSuppressed is recorded when an event is not created because of a suppression window, mute, staleness or rate limit.
+	OutcomeSuppressed = "suppressed"
+	// OutcomeDeduplicated is recorded when an event is held because the alarm is flapping.
+	OutcomeDeduplicated = "deduplicated"
+	// OutcomeFlapping is recorded when a flapping event is created in place of the alarm event.
+	OutcomeFlapping = "flapping"
+	// OutcomeDenied is recorded when the allowlist does not permit the event, or a selector matches too many objects.
+	OutcomeDenied = "denied"
+	// OutcomeDropped is recorded when the target was not found and the fallback drops the event.
+	OutcomeDropped = "dropped"
+)
+
+// Event which was created or updated.
+type Event struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid,omitempty"`
+	Operation string `json:"operation"`
+	// Object the event is associated with eg. "Pod/drupal".
+	Object string `json:"object,omitempty"`
+}
+
+// Decision not to create an event.
+type Decision struct {
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason,omitempty"`
+	Object  string `json:"object,omitempty"`
+}
+
+// Action which was executed, dry run, skipped or failed.
+type Action struct {
+	Action  string `json:"action"`
+	Object  string `json:"object"`
+	DryRun  bool   `json:"dryRun,omitempty"`
+	Skipped string `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SinkFailure is a sink which did not deliver the alarm.
+type SinkFailure struct {
+	Sink       string `json:"sink"`
+	Error      string `json:"error"`
+	ErrorClass string `json:"errorClass,omitempty"`
+}
+
+// Target describes what was done for an alarm sent to a target.
+type Target struct {
+	Record    string     `json:"record,omitempty"`
+	Alarm     string     `json:"alarm,omitempty"`
+	Cluster   string     `json:"cluster,omitempty"`
+	Target    string     `json:"target,omitempty"`
+	Sinks     []string   `json:"sinks,omitempty"`
+	Events    []Event    `json:"events,omitempty"`
+	Decisions []Decision `json:"decisions,omitempty"`
+	Actions   []Action   `json:"actions,omitempty"`
+	// SinkFailures are sinks which failed while others delivered the alarm, or all sinks when none did.
+	SinkFailures []SinkFailure `json:"sinkFailures,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	ErrorClass   string        `json:"errorClass,omitempty"`
+
+	mu sync.Mutex
+}
+
+// Event records an event which was created or updated.
+func (t *Target) Event(event Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Events = append(t.Events, event)
+}
+
+// Decision records why an event was not created.
+func (t *Target) Decision(outcome, reason, object string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Decisions = append(t.Decisions, Decision{Outcome: outcome, Reason: reason, Object: object})
+}
+
+// Action records an action which was executed, dry run or skipped.
+func (t *Target) Action(action Action) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Actions = append(t.Actions, action)
+}
+
+// SinkFailed records a sink which did not deliver the alarm.
+func (t *Target) SinkFailed(sink string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.SinkFailures = append(t.SinkFailures, SinkFailure{Sink: sink, Error: err.Error(), ErrorClass: metrics.Classify(err)})
+}
+
+// Fail records the error and its class.
+func (t *Target) Fail(err error) {
+	if err == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Error = err.Error()
+	t.ErrorClass = metrics.Classify(err)
+}
+
+type contextKey struct{}
+
+// WithContext returns a copy of the context which carries the target report.
+func WithContext(ctx context.Context, target *Target) context.Context {
+	return context.WithValue(ctx, contextKey{}, target)
+}
+
+// FromContext returns the target report carried by the context, or a report which is discarded.
+func FromContext(ctx context.Context) *Target {
+	if target, ok := ctx.Value(contextKey{}).(*Target); ok {
+		return target
+	}
+
+	return &Target{}
+}
@@ -0,0 +1,39 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTarget(t *testing.T) {
+	target := &Target{Alarm: "drupal-5xx"}
+
+	ctx := WithContext(context.TODO(), target)
+
+	FromContext(ctx).Event(Event{Namespace: "test", Name: "aws-cloudwatch-alarm-abc", UID: "123", Operation: OperationCreated, Object: "Pod/drupal"})
+	FromContext(ctx).Decision(OutcomeSuppressed, "muted", "Pod/other")
+	FromContext(ctx).Action(Action{Action: "rollout-restart", Object: "Deployment/drupal", DryRun: true})
+	FromContext(ctx).SinkFailed("slack", fmt.Errorf("connection refused"))
+	FromContext(ctx).Fail(nil)
+
+	assert.Len(t, target.Events, 1)
+	assert.Equal(t, []SinkFailure{{Sink: "slack", Error: "connection refused", ErrorClass: "Internal"}}, target.SinkFailures)
+	assert.Len(t, target.Decisions, 1)
+	assert.Len(t, target.Actions, 1)
+	assert.Empty(t, target.Error)
+
+	target.Fail(fmt.Errorf("failed"))
+	assert.Equal(t, "failed", target.Error)
+	assert.Equal(t, "Internal", target.ErrorClass)
+
+	data, err := json.Marshal(target)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"operation":"created"`)
+
+	// Reports are discarded when the context does not carry one.
+	FromContext(context.TODO()).Decision(OutcomeDenied, "", "")
+}
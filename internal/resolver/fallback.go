@@ -0,0 +1,58 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+)
+
+const (
+	// FallbackNone returns an error when the target object is missing.
+	FallbackNone = "none"
+	// FallbackNamespace associates the event with the target's Namespace.
+	FallbackNamespace = "namespace"
+	// FallbackDangling associates the event with the target's name and kind, without a UID.
+	FallbackDangling = "dangling"
+	// FallbackDrop discards the event.
+	FallbackDrop = "drop"
+)
+
+// Fallback returns the reference an event should be associated with when the target object is missing.
+// A nil reference means the event should be dropped.
+func (r *Resolver) Fallback(ctx context.Context, t target.Target, mode string) (*corev1.ObjectReference, error) {
+	switch mode {
+	case FallbackNone, "":
+		return nil, fmt.Errorf("no fallback configured")
+	case FallbackDrop:
+		return nil, nil
+	case FallbackDangling:
+		if t.Name == "" {
+			return nil, fmt.Errorf("dangling fallback requires a name")
+		}
+
+		return &corev1.ObjectReference{
+			APIVersion: t.GroupVersionKind().GroupVersion().String(),
+			Kind:       t.Kind,
+			Namespace:  t.Namespace,
+			Name:       t.Name,
+		}, nil
+	case FallbackNamespace:
+		if t.Namespace == "" {
+			return nil, fmt.Errorf("namespace fallback requires a namespaced target")
+		}
+
+		namespace, err := r.Namespace(ctx, t.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		ref := Reference(namespace)
+
+		return &ref, nil
+	}
+
+	return nil, fmt.Errorf("unknown fallback: %s", mode)
+}
@@ -0,0 +1,158 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+)
+
+const (
+	// OwnerController walks owner references up to the top-level controller.
+	OwnerController = "controller"
+
+	// OwnerModeReplace emits the event on the owner instead of the original object.
+	OwnerModeReplace = "replace"
+	// OwnerModeInclude emits the event on the owner as well as the original object.
+	OwnerModeInclude = "include"
+)
+
+var (
+	// ErrOwnerNotFound is returned when the owner kind is not in the object's owner references.
+	ErrOwnerNotFound = errors.New("owner not found")
+
+	// errOwnerDenied is returned when an owner kind is not allowed, so it is not read.
+	errOwnerDenied = errors.New("owner denied")
+)
+
+// Allowed returns false if the kind can't be used, after recording why.
+type Allowed func(group, kind, name string) bool
+
+// Owners walks the owner references of each object as configured by the target. An object without an owner of the
+// target's kind is used in its place. Owners are checked with allowed before they are read, and objects with an owner
+// which is not allowed are skipped, although the object itself is still included when the mode is include.
+func (r *Resolver) Owners(ctx context.Context, t target.Target, objects []*unstructured.Unstructured, allowed Allowed) ([]*unstructured.Unstructured, error) {
+	if t.Owner == "" {
+		return objects, nil
+	}
+
+	var (
+		result []*unstructured.Unstructured
+		seen   = make(map[types.UID]bool)
+	)
+
+	add := func(object *unstructured.Unstructured) {
+		if seen[object.GetUID()] {
+			return
+		}
+
+		seen[object.GetUID()] = true
+		result = append(result, object)
+	}
+
+	logger := logging.FromContext(ctx)
+
+	if t.OwnerMode != OwnerModeReplace && t.OwnerMode != OwnerModeInclude && t.OwnerMode != "" {
+		return nil, fmt.Errorf("unknown owner mode: %s", t.OwnerMode)
+	}
+
+	for _, object := range objects {
+		if t.OwnerMode == OwnerModeInclude {
+			add(object)
+		}
+
+		owner, err := r.owner(ctx, object, t.Owner, allowed)
+		if errors.Is(err, errOwnerDenied) {
+			continue
+		}
+
+		if errors.Is(err, ErrOwnerNotFound) {
+			logger.Warn("Owner not found, using the object", "kind", object.GetKind(), "name", object.GetName(), "owner_kind", t.Owner)
+			add(object)
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to find owner of %s/%s: %w", object.GetKind(), object.GetName(), err)
+		}
+
+		logger.Info("Found owner", "kind", object.GetKind(), "name", object.GetName(), "owner_kind", owner.GetKind(), "owner_name", owner.GetName())
+
+		add(owner)
+	}
+
+	return result, nil
+}
+
+// owner walks up the owner references until an object of the given kind is found, or the top-level controller
+// is reached when kind is OwnerController. The top-level controller of an unowned object is the object itself.
+func (r *Resolver) owner(ctx context.Context, object *unstructured.Unstructured, kind string, allowed Allowed) (*unstructured.Unstructured, error) {
+	current := object
+
+	for depth := 0; depth < r.options.OwnerDepth; depth++ {
+		ref := controllerRef(current)
+		if ref == nil {
+			break
+		}
+
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse owner api version: %w", err)
+		}
+
+		if !allowed(gv.Group, ref.Kind, ref.Name) {
+			return nil, errOwnerDenied
+		}
+
+		mapping, err := r.mapper.RESTMapping(gv.WithKind(ref.Kind).GroupKind(), gv.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map owner kind to resource: %w", err)
+		}
+
+		// Owners are either in the same namespace or cluster-scoped.
+		namespace := current.GetNamespace()
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			namespace = ""
+		}
+
+		parent, err := r.client.Resource(mapping.Resource).Namespace(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+
+		current = parent
+
+		if current.GetKind() == kind {
+			return current, nil
+		}
+	}
+
+	// An object without a controller is its own top-level controller.
+	if kind == OwnerController {
+		return current, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrOwnerNotFound, kind)
+}
+
+// controllerRef returns the controller owner reference, or the first owner reference if there is no controller.
+func controllerRef(object *unstructured.Unstructured) *metav1.OwnerReference {
+	refs := object.GetOwnerReferences()
+	if len(refs) == 0 {
+		return nil
+	}
+
+	if ref := metav1.GetControllerOfNoCopy(object); ref != nil {
+		return ref
+	}
+
+	return &refs[0]
+}
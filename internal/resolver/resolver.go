@@ -0,0 +1,217 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+)
+
+const (
+	// DefaultLimit is the maximum number of objects a selector can match.
+	DefaultLimit = 10
+	// DefaultOwnerDepth is the maximum number of owner references which will be walked.
+	DefaultOwnerDepth = 5
+)
+
+// ErrNotFound is returned when the target does not refer to any objects.
+var ErrNotFound = errors.New("target object not found")
+
+// ErrLimit is returned when a selector matches more objects than the limit. Retrying will not succeed.
+var ErrLimit = errors.New("selector limit exceeded")
+
+// Options for resolving objects.
+type Options struct {
+	// Limit is the maximum number of objects a selector can match.
+	Limit int
+	// OwnerDepth is the maximum number of owner references which will be walked.
+	OwnerDepth int
+}
+
+// Resolver finds the Kubernetes objects which a target refers to.
+type Resolver struct {
+	client  dynamic.Interface
+	mapper  meta.RESTMapper
+	options Options
+}
+
+// New creates a new resolver.
+func New(client dynamic.Interface, mapper meta.RESTMapper, options Options) *Resolver {
+	if options.Limit <= 0 {
+		options.Limit = DefaultLimit
+	}
+
+	if options.OwnerDepth <= 0 {
+		options.OwnerDepth = DefaultOwnerDepth
+	}
+
+	return &Resolver{
+		client:  client,
+		mapper:  mapper,
+		options: options,
+	}
+}
+
+// Namespaced uses discovery to determine if the target kind is namespaced.
+func (r *Resolver) Namespaced(t target.Target) (bool, error) {
+	gvk := t.GroupVersionKind()
+
+	mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, fmt.Errorf("failed to map kind to resource: %w", err)
+	}
+
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+// Resolve returns the objects which the target refers to, either by name or by label selector.
+func (r *Resolver) Resolve(ctx context.Context, t target.Target) ([]*unstructured.Unstructured, error) {
+	gvk := t.GroupVersionKind()
+
+	mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map kind to resource: %w", err)
+	}
+
+	namespace, err := scope(mapping, t.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := r.client.Resource(mapping.Resource).Namespace(namespace)
+
+	if t.Selector == "" {
+		object, err := resource.Get(ctx, t.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, t.Kind, t.Name)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to get object: %w", err)
+		}
+
+		return []*unstructured.Unstructured{object}, nil
+	}
+
+	selector, err := labels.Parse(t.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse selector: %w", err)
+	}
+
+	list, err := resource.List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+		// Request one more than the limit so we can tell when it has been exceeded.
+		Limit: int64(r.options.Limit + 1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	var names []string
+
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+
+	logging.FromContext(ctx).Info("Selector matched objects", "selector", t.Selector, "count", len(names), "names", names)
+
+	if len(list.Items) == 0 {
+		return nil, fmt.Errorf("%w: %s matching %q", ErrNotFound, t.Kind, t.Selector)
+	}
+
+	if len(list.Items) > r.options.Limit {
+		return nil, fmt.Errorf("%w: selector matched more than %d objects", ErrLimit, r.options.Limit)
+	}
+
+	objects := make([]*unstructured.Unstructured, len(list.Items))
+
+	for i := range list.Items {
+		objects[i] = &list.Items[i]
+	}
+
+	return objects, nil
+}
+
+// scope returns the namespace to use for the mapping. Cluster-scoped kinds ignore the namespace.
+func scope(mapping *meta.RESTMapping, namespace string) (string, error) {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return "", nil
+	}
+
+	if namespace == "" {
+		return "", fmt.Errorf("namespace is required for %s", mapping.GroupVersionKind.Kind)
+	}
+
+	return namespace, nil
+}
+
+// Namespace returns the namespace object with the given name.
+func (r *Resolver) Namespace(ctx context.Context, name string) (*unstructured.Unstructured, error) {
+	namespace, err := r.client.Resource(schema.GroupVersionResource{
+		Version:  "v1",
+		Resource: "namespaces",
+	}).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	return namespace, nil
+}
+
+// Annotate sets an annotation on the object.
+func (r *Resolver) Annotate(ctx context.Context, object *unstructured.Unstructured, key, value string) error {
+	return r.Patch(ctx, object, map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{
+				key: value,
+			},
+		},
+	})
+}
+
+// Patch the object using a JSON merge patch.
+func (r *Resolver) Patch(ctx context.Context, object *unstructured.Unstructured, patch map[string]any) error {
+	gvk := object.GroupVersionKind()
+
+	mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return fmt.Errorf("failed to map kind to resource: %w", err)
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+
+	_, err = r.client.Resource(mapping.Resource).Namespace(object.GetNamespace()).Patch(ctx, object.GetName(), types.MergePatchType, data, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to patch object: %w", err)
+	}
+
+	return nil
+}
+
+// Reference returns an object reference which can be used as the involved object of an event.
+func Reference(object *unstructured.Unstructured) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion:      object.GetAPIVersion(),
+		Kind:            object.GetKind(),
+		Namespace:       object.GetNamespace(),
+		Name:            object.GetName(),
+		UID:             object.GetUID(),
+		ResourceVersion: object.GetResourceVersion(),
+	}
+}
@@ -0,0 +1,259 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+)
+
+var (
+	podGVK        = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	replicaSetGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+	deploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	nodeGVK       = schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+)
+
+func newObject(gvk schema.GroupVersionKind, name string, owner *unstructured.Unstructured) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(gvk)
+	object.SetNamespace("test")
+	object.SetName(name)
+	object.SetUID(types.UID(name))
+
+	if owner != nil {
+		object.SetOwnerReferences([]metav1.OwnerReference{
+			{
+				APIVersion: owner.GetAPIVersion(),
+				Kind:       owner.GetKind(),
+				Name:       owner.GetName(),
+				UID:        owner.GetUID(),
+				Controller: ptr.To(true),
+			},
+		})
+	}
+
+	return object
+}
+
+func newPod(name string, labels map[string]string) *unstructured.Unstructured {
+	pod := &unstructured.Unstructured{}
+	pod.SetGroupVersionKind(podGVK)
+	pod.SetNamespace("test")
+	pod.SetName(name)
+	pod.SetLabels(labels)
+	return pod
+}
+
+func newResolver(limit int, objects ...runtime.Object) *Resolver {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(podGVK, meta.RESTScopeNamespace)
+	mapper.Add(replicaSetGVK, meta.RESTScopeNamespace)
+	mapper.Add(deploymentGVK, meta.RESTScopeNamespace)
+	mapper.Add(nodeGVK, meta.RESTScopeRoot)
+
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+
+	return New(client, mapper, Options{Limit: limit})
+}
+
+func TestResolveByName(t *testing.T) {
+	r := newResolver(0, newPod("foo", nil))
+
+	objects, err := r.Resolve(context.TODO(), target.Target{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  "test",
+		Name:       "foo",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, "foo", objects[0].GetName())
+}
+
+func TestResolveBySelector(t *testing.T) {
+	r := newResolver(2,
+		newPod("foo", map[string]string{"app": "drupal"}),
+		newPod("bar", map[string]string{"app": "drupal"}),
+		newPod("baz", map[string]string{"app": "other"}),
+	)
+
+	objects, err := r.Resolve(context.TODO(), target.Target{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  "test",
+		Selector:   "app=drupal",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+}
+
+func TestResolveBySelectorLimit(t *testing.T) {
+	r := newResolver(1,
+		newPod("foo", map[string]string{"app": "drupal"}),
+		newPod("bar", map[string]string{"app": "drupal"}),
+	)
+
+	_, err := r.Resolve(context.TODO(), target.Target{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  "test",
+		Selector:   "app=drupal",
+	})
+	assert.ErrorContains(t, err, "selector matched more than 1 objects")
+	assert.ErrorIs(t, err, ErrLimit)
+}
+
+func TestOwners(t *testing.T) {
+	var (
+		deployment = newObject(deploymentGVK, "drupal", nil)
+		replicaSet = newObject(replicaSetGVK, "drupal-abc", deployment)
+		pod        = newObject(podGVK, "drupal-abc-123", replicaSet)
+		r          = newResolver(0, deployment, replicaSet, pod)
+	)
+
+	objects, err := r.Owners(context.TODO(), target.Target{Owner: "ReplicaSet"}, []*unstructured.Unstructured{pod}, allowAll)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, "drupal-abc", objects[0].GetName())
+
+	objects, err = r.Owners(context.TODO(), target.Target{Owner: OwnerController, OwnerMode: OwnerModeInclude}, []*unstructured.Unstructured{pod}, allowAll)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, "drupal-abc-123", objects[0].GetName())
+	assert.Equal(t, "drupal", objects[1].GetName())
+
+	// The walk is stopped before a kind which is not allowed is read.
+	var (
+		checked []string
+		client  = r.client.(*dynamicfake.FakeDynamicClient)
+	)
+
+	client.ClearActions()
+
+	objects, err = r.Owners(context.TODO(), target.Target{Owner: "Deployment", OwnerMode: OwnerModeInclude}, []*unstructured.Unstructured{pod},
+		func(group, kind, name string) bool {
+			checked = append(checked, kind+"/"+name)
+			return kind != "ReplicaSet"
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ReplicaSet/drupal-abc"}, checked)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, "drupal-abc-123", objects[0].GetName())
+	assert.Empty(t, client.Actions())
+}
+
+func TestOwnersUnowned(t *testing.T) {
+	var (
+		deployment = newObject(deploymentGVK, "drupal", nil)
+		replicaSet = newObject(replicaSetGVK, "drupal-abc", deployment)
+		pod        = newObject(podGVK, "drupal-abc-123", replicaSet)
+		bare       = newObject(podGVK, "bare", nil)
+		r          = newResolver(0, deployment, replicaSet, pod, bare)
+	)
+
+	// An unowned target is its own controller, and does not stop the other matches from being walked.
+	objects, err := r.Owners(context.TODO(), target.Target{Owner: OwnerController}, []*unstructured.Unstructured{bare, pod}, allowAll)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, "bare", objects[0].GetName())
+	assert.Equal(t, "drupal", objects[1].GetName())
+
+	objects, err = r.Owners(context.TODO(), target.Target{Owner: OwnerController, OwnerMode: OwnerModeInclude}, []*unstructured.Unstructured{deployment}, allowAll)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, "drupal", objects[0].GetName())
+
+	// Objects without an owner of the kind are used in its place, so the other matches are still walked.
+	var buf bytes.Buffer
+
+	ctx := logging.WithContext(context.TODO(), logging.New(&buf))
+
+	objects, err = r.Owners(ctx, target.Target{Owner: "Deployment"}, []*unstructured.Unstructured{bare, pod}, allowAll)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, "bare", objects[0].GetName())
+	assert.Equal(t, "drupal", objects[1].GetName())
+	assert.Contains(t, buf.String(), "Owner not found, using the object")
+
+	objects, err = r.Owners(context.TODO(), target.Target{Owner: "StatefulSet"}, []*unstructured.Unstructured{pod}, allowAll)
+	assert.NoError(t, err)
+	assert.Equal(t, []*unstructured.Unstructured{pod}, objects)
+}
+
+func allowAll(group, kind, name string) bool {
+	return true
+}
+
+func TestFallback(t *testing.T) {
+	namespace := &unstructured.Unstructured{}
+	namespace.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"})
+	namespace.SetName("test")
+	namespace.SetUID("namespace-uid")
+
+	var (
+		r       = newResolver(0, namespace)
+		missing = target.Target{APIVersion: "v1", Kind: "Pod", Namespace: "test", Name: "missing"}
+	)
+
+	_, err := r.Resolve(context.TODO(), missing)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	ref, err := r.Fallback(context.TODO(), missing, FallbackNamespace)
+	assert.NoError(t, err)
+	assert.Equal(t, "Namespace", ref.Kind)
+	assert.Equal(t, types.UID("namespace-uid"), ref.UID)
+
+	ref, err = r.Fallback(context.TODO(), missing, FallbackDangling)
+	assert.NoError(t, err)
+	assert.Equal(t, "missing", ref.Name)
+	assert.Empty(t, ref.UID)
+
+	ref, err = r.Fallback(context.TODO(), missing, FallbackDrop)
+	assert.NoError(t, err)
+	assert.Nil(t, ref)
+
+	_, err = r.Fallback(context.TODO(), missing, FallbackNone)
+	assert.Error(t, err)
+}
+
+func TestResolveClusterScoped(t *testing.T) {
+	node := &unstructured.Unstructured{}
+	node.SetGroupVersionKind(nodeGVK)
+	node.SetName("node-1")
+
+	r := newResolver(0, node)
+
+	namespaced, err := r.Namespaced(target.Target{APIVersion: "v1", Kind: "Node"})
+	assert.NoError(t, err)
+	assert.False(t, namespaced)
+
+	// The namespace is ignored for cluster-scoped kinds.
+	objects, err := r.Resolve(context.TODO(), target.Target{
+		APIVersion: "v1",
+		Kind:       "Node",
+		Namespace:  "test",
+		Name:       "node-1",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+
+	_, err = r.Resolve(context.TODO(), target.Target{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       "foo",
+	})
+	assert.ErrorContains(t, err, "namespace is required")
+}
@@ -0,0 +1,382 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+const (
+	// KindDeployment rollouts are found using ReplicaSet revisions.
+	KindDeployment = "Deployment"
+	// KindStatefulSet rollouts are found using ControllerRevisions.
+	KindStatefulSet = "StatefulSet"
+
+	// AnnotationRevision is the annotation Kubernetes sets on ReplicaSets with the Deployment revision.
+	AnnotationRevision = "deployment.kubernetes.io/revision"
+	// ConditionProgressing is the Deployment condition which is updated as it rolls out.
+	ConditionProgressing = "Progressing"
+
+	// DefaultLookback is the default window which rollouts are correlated with alarms over.
+	DefaultLookback = time.Hour
+)
+
+var (
+	deployments         = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	statefulSets        = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	replicaSets         = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+	controllerRevisions = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "controllerrevisions"}
+)
+
+// Rollout of a Deployment or StatefulSet.
+type Rollout struct {
+	// Object which was rolled out eg. "Deployment/drupal".
+	Object   string
+	Revision string
+	Image    string
+	Time     time.Time
+}
+
+// Summary of the rollout relative to now eg. "last rollout 5 minutes ago (revision 4, image drupal:1.2)".
+func (r Rollout) Summary(now time.Time) string {
+	details := []string{"revision " + r.Revision}
+
+	if r.Image != "" {
+		details = append(details, "image "+r.Image)
+	}
+
+	return fmt.Sprintf("last rollout %s (%s)", ago(now.Sub(r.Time)), strings.Join(details, ", "))
+}
+
+// Annotations containing the rollout for tools.
+func (r Rollout) Annotations() map[string]string {
+	annotations := map[string]string{
+		annotation.KeyRolloutAt:       r.Time.UTC().Format(time.RFC3339),
+		annotation.KeyRolloutObject:   r.Object,
+		annotation.KeyRolloutRevision: r.Revision,
+	}
+
+	if r.Image != "" {
+		annotations[annotation.KeyRolloutImage] = r.Image
+	}
+
+	return annotations
+}
+
+// ago formats the duration in minutes eg. "5 minutes ago".
+func ago(d time.Duration) string {
+	switch minutes := int(d.Minutes()); minutes {
+	case 0:
+		return "less than a minute ago"
+	case 1:
+		return "1 minute ago"
+	default:
+		return fmt.Sprintf("%d minutes ago", minutes)
+	}
+}
+
+// Finder looks up the rollout history of objects.
+type Finder struct {
+	client   dynamic.Interface
+	lookback time.Duration
+}
+
+// New creates a finder which returns rollouts within the lookback window.
+func New(client dynamic.Interface, lookback time.Duration) *Finder {
+	if lookback <= 0 {
+		lookback = DefaultLookback
+	}
+
+	return &Finder{
+		client:   client,
+		lookback: lookback,
+	}
+}
+
+// Latest returns the most recent rollout within the lookback window for the object. See Session.Latest.
+func (f *Finder) Latest(ctx context.Context, object *unstructured.Unstructured, now time.Time) (*Rollout, error) {
+	return f.Session().Latest(ctx, object, now)
+}
+
+// Session returns a lookup which caches lists, so objects in the same namespace are only listed once. A session should
+// only be used for a single forward so the history is not stale.
+func (f *Finder) Session() *Session {
+	return &Session{
+		finder: f,
+		lists:  make(map[string][]unstructured.Unstructured),
+	}
+}
+
+// Session looks up rollouts using cached lists.
+type Session struct {
+	finder *Finder
+	lists  map[string][]unstructured.Unstructured
+}
+
+// Latest returns the most recent rollout within the lookback window for the object, which is either a Deployment or
+// StatefulSet, or owns them eg. a Skpr Environment. Nil is returned if there was no rollout.
+func (s *Session) Latest(ctx context.Context, object *unstructured.Unstructured, now time.Time) (*Rollout, error) {
+	var (
+		latest *Rollout
+		err    error
+	)
+
+	gvk := object.GroupVersionKind()
+
+	switch {
+	case gvk.Group == "apps" && gvk.Kind == KindDeployment:
+		latest, err = s.deployment(ctx, object)
+	case gvk.Group == "apps" && gvk.Kind == KindStatefulSet:
+		latest, err = s.statefulSet(ctx, object)
+	default:
+		latest, err = s.owned(ctx, object)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if latest == nil || latest.Time.Before(now.Add(-s.finder.lookback)) {
+		return nil, nil
+	}
+
+	return latest, nil
+}
+
+// owned returns the latest rollout of the Deployments and StatefulSets owned by the object.
+func (s *Session) owned(ctx context.Context, object *unstructured.Unstructured) (*Rollout, error) {
+	if object.GetNamespace() == "" {
+		return nil, nil
+	}
+
+	var latest *Rollout
+
+	for _, resource := range []schema.GroupVersionResource{deployments, statefulSets} {
+		children, err := s.children(ctx, resource, object, labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, child := range children {
+			var r *Rollout
+
+			if resource == deployments {
+				r, err = s.deployment(ctx, child)
+			} else {
+				r, err = s.statefulSet(ctx, child)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			if r != nil && (latest == nil || r.Time.After(latest.Time)) {
+				latest = r
+			}
+		}
+	}
+
+	return latest, nil
+}
+
+// deployment returns the rollout for the ReplicaSet with the highest revision.
+func (s *Session) deployment(ctx context.Context, deployment *unstructured.Unstructured) (*Rollout, error) {
+	selector, err := workloadSelector(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := s.children(ctx, replicaSets, deployment, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		latest   *Rollout
+		revision int64 = -1
+	)
+
+	for _, rs := range children {
+		value, err := strconv.ParseInt(rs.GetAnnotations()[AnnotationRevision], 10, 64)
+		if err != nil || value <= revision {
+			continue
+		}
+
+		revision = value
+		latest = &Rollout{
+			Object:   fmt.Sprintf("%s/%s", KindDeployment, deployment.GetName()),
+			Revision: strconv.FormatInt(value, 10),
+			Image:    images(rs.Object, "spec", "template", "spec", "containers"),
+			Time:     rs.GetCreationTimestamp().Time,
+		}
+	}
+
+	// A rollback reuses the old ReplicaSet with a new revision, so its creation time is when it was first rolled out.
+	// The Progressing condition is updated when the Deployment rolls out to it again.
+	if latest != nil {
+		if updated := progressing(deployment); updated.After(latest.Time) {
+			latest.Time = updated
+		}
+	}
+
+	return latest, nil
+}
+
+// progressing returns when the Progressing condition of the Deployment was last updated.
+func progressing(deployment *unstructured.Unstructured) time.Time {
+	conditions, _, _ := unstructured.NestedSlice(deployment.Object, "status", "conditions")
+
+	for _, condition := range conditions {
+		c, ok := condition.(map[string]any)
+		if !ok || c["type"] != ConditionProgressing {
+			continue
+		}
+
+		value, _ := c["lastUpdateTime"].(string)
+
+		updated, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}
+		}
+
+		return updated
+	}
+
+	return time.Time{}
+}
+
+// statefulSet returns the rollout for the ControllerRevision with the highest revision.
+func (s *Session) statefulSet(ctx context.Context, statefulSet *unstructured.Unstructured) (*Rollout, error) {
+	selector, err := workloadSelector(statefulSet)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := s.children(ctx, controllerRevisions, statefulSet, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		latest   *Rollout
+		revision int64 = -1
+	)
+
+	for _, cr := range children {
+		value, _, err := unstructured.NestedInt64(cr.Object, "revision")
+		if err != nil || value <= revision {
+			continue
+		}
+
+		image := images(cr.Object, "data", "spec", "template", "spec", "containers")
+		if image == "" {
+			image = images(statefulSet.Object, "spec", "template", "spec", "containers")
+		}
+
+		revision = value
+		latest = &Rollout{
+			Object:   fmt.Sprintf("%s/%s", KindStatefulSet, statefulSet.GetName()),
+			Revision: strconv.FormatInt(value, 10),
+			Image:    image,
+			Time:     written(cr),
+		}
+	}
+
+	return latest, nil
+}
+
+// written returns when the object was last written. A rollback reuses the old ControllerRevision and only updates its
+// revision, which is recorded in its managed fields, so its creation time is when it was first rolled out.
+func written(object *unstructured.Unstructured) time.Time {
+	result := object.GetCreationTimestamp().Time
+
+	for _, entry := range object.GetManagedFields() {
+		if entry.Subresource == "" && entry.Time != nil && entry.Time.After(result) {
+			result = entry.Time.Time
+		}
+	}
+
+	return result
+}
+
+// children lists the objects matching the selector in the owner's namespace which are owned by it. Lists are cached by
+// resource, namespace and selector.
+func (s *Session) children(ctx context.Context, resource schema.GroupVersionResource, owner *unstructured.Unstructured, selector labels.Selector) ([]*unstructured.Unstructured, error) {
+	key := fmt.Sprintf("%s/%s?%s", resource.Resource, owner.GetNamespace(), selector.String())
+
+	items, ok := s.lists[key]
+	if !ok {
+		list, err := s.finder.client.Resource(resource).Namespace(owner.GetNamespace()).List(ctx, metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", resource.Resource, err)
+		}
+
+		items = list.Items
+		s.lists[key] = items
+	}
+
+	var children []*unstructured.Unstructured
+
+	for i := range items {
+		for _, ref := range items[i].GetOwnerReferences() {
+			if ref.UID == owner.GetUID() {
+				children = append(children, &items[i])
+				break
+			}
+		}
+	}
+
+	return children, nil
+}
+
+// workloadSelector returns the label selector of a Deployment or StatefulSet, which its ReplicaSets and
+// ControllerRevisions also match.
+func workloadSelector(object *unstructured.Unstructured) (labels.Selector, error) {
+	value, found, err := unstructured.NestedMap(object.Object, "spec", "selector")
+	if err != nil || !found {
+		return labels.Everything(), nil
+	}
+
+	var selector metav1.LabelSelector
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(value, &selector); err != nil {
+		return nil, fmt.Errorf("failed to convert selector of %s/%s: %w", object.GetKind(), object.GetName(), err)
+	}
+
+	result, err := metav1.LabelSelectorAsSelector(&selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse selector of %s/%s: %w", object.GetKind(), object.GetName(), err)
+	}
+
+	return result, nil
+}
+
+// images returns the comma separated container images at the path.
+func images(object map[string]any, fields ...string) string {
+	containers, _, _ := unstructured.NestedSlice(object, fields...)
+
+	var list []string
+
+	for _, container := range containers {
+		if c, ok := container.(map[string]any); ok {
+			if image, ok := c["image"].(string); ok && image != "" {
+				list = append(list, image)
+			}
+		}
+	}
+
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,175 @@
+package rollout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newObject(apiVersion, kind, name, uid string, owner *unstructured.Unstructured, created time.Time) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(apiVersion)
+	object.SetKind(kind)
+	object.SetNamespace("test")
+	object.SetName(name)
+	object.SetUID(types.UID(uid))
+	object.SetCreationTimestamp(metav1.NewTime(created))
+
+	if owner != nil {
+		object.SetOwnerReferences([]metav1.OwnerReference{
+			{APIVersion: owner.GetAPIVersion(), Kind: owner.GetKind(), Name: owner.GetName(), UID: owner.GetUID()},
+		})
+	}
+
+	return object
+}
+
+func newReplicaSet(t *testing.T, deployment *unstructured.Unstructured, name, revision, image string, created time.Time) *unstructured.Unstructured {
+	rs := newObject("apps/v1", "ReplicaSet", name, name, deployment, created)
+	rs.SetAnnotations(map[string]string{AnnotationRevision: revision})
+	assert.NoError(t, unstructured.SetNestedSlice(rs.Object, []any{map[string]any{"name": "app", "image": image}}, "spec", "template", "spec", "containers"))
+	return rs
+}
+
+func newClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		deployments:         "DeploymentList",
+		statefulSets:        "StatefulSetList",
+		replicaSets:         "ReplicaSetList",
+		controllerRevisions: "ControllerRevisionList",
+	}, objects...)
+}
+
+func newFinder(objects ...runtime.Object) *Finder {
+	return New(newClient(objects...), time.Hour)
+}
+
+func TestLatestDeployment(t *testing.T) {
+	deployment := newObject("apps/v1", "Deployment", "drupal", "deployment", nil, now.Add(-48*time.Hour))
+
+	f := newFinder(
+		deployment,
+		newReplicaSet(t, deployment, "drupal-1", "9", "drupal:1.0", now.Add(-24*time.Hour)),
+		newReplicaSet(t, deployment, "drupal-2", "10", "drupal:1.1", now.Add(-5*time.Minute)),
+		newReplicaSet(t, newObject("apps/v1", "Deployment", "other", "other", nil, now), "other-1", "11", "other:1.0", now),
+	)
+
+	latest, err := f.Latest(context.TODO(), deployment, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "Deployment/drupal", latest.Object)
+	assert.Equal(t, "10", latest.Revision)
+	assert.Equal(t, "drupal:1.1", latest.Image)
+	assert.True(t, now.Add(-5*time.Minute).Equal(latest.Time))
+
+	assert.Equal(t, "last rollout 5 minutes ago (revision 10, image drupal:1.1)", latest.Summary(now))
+	assert.Equal(t, map[string]string{
+		annotation.KeyRolloutAt:       "2024-06-01T11:55:00Z",
+		annotation.KeyRolloutObject:   "Deployment/drupal",
+		annotation.KeyRolloutRevision: "10",
+		annotation.KeyRolloutImage:    "drupal:1.1",
+	}, latest.Annotations())
+
+	// Rollouts outside the lookback window are not correlated.
+	latest, err = f.Latest(context.TODO(), deployment, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+}
+
+func TestLatestDeploymentRollback(t *testing.T) {
+	deployment := newObject("apps/v1", "Deployment", "drupal", "deployment", nil, now.Add(-48*time.Hour))
+	assert.NoError(t, unstructured.SetNestedSlice(deployment.Object, []any{
+		map[string]any{"type": "Available", "status": "True", "lastUpdateTime": "2024-06-01T11:59:00Z"},
+		map[string]any{"type": ConditionProgressing, "status": "True", "lastUpdateTime": "2024-06-01T11:57:00Z"},
+	}, "status", "conditions"))
+
+	// The undo reused the ReplicaSet from yesterday with a new revision.
+	f := newFinder(
+		deployment,
+		newReplicaSet(t, deployment, "drupal-1", "11", "drupal:1.0", now.Add(-24*time.Hour)),
+		newReplicaSet(t, deployment, "drupal-2", "10", "drupal:1.1", now.Add(-2*time.Hour)),
+	)
+
+	latest, err := f.Latest(context.TODO(), deployment, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "last rollout 3 minutes ago (revision 11, image drupal:1.0)", latest.Summary(now))
+}
+
+func TestLatestStatefulSet(t *testing.T) {
+	sts := newObject("apps/v1", "StatefulSet", "mysql", "sts", nil, now.Add(-48*time.Hour))
+	assert.NoError(t, unstructured.SetNestedSlice(sts.Object, []any{map[string]any{"name": "mysql", "image": "mysql:8"}}, "spec", "template", "spec", "containers"))
+
+	revision := newObject("apps/v1", "ControllerRevision", "mysql-abc", "cr", sts, now.Add(-90*time.Second))
+	assert.NoError(t, unstructured.SetNestedField(revision.Object, int64(3), "revision"))
+
+	latest, err := newFinder(sts, revision).Latest(context.TODO(), sts, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "last rollout 1 minute ago (revision 3, image mysql:8)", latest.Summary(now))
+
+	// A rollback reuses an old revision, which is only updated with the new revision number.
+	rollback := newObject("apps/v1", "ControllerRevision", "mysql-old", "old", sts, now.Add(-24*time.Hour))
+	assert.NoError(t, unstructured.SetNestedField(rollback.Object, int64(4), "revision"))
+	rollback.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate, Time: ptr.To(metav1.NewTime(now.Add(-2 * time.Minute)))},
+	})
+
+	latest, err = newFinder(sts, revision, rollback).Latest(context.TODO(), sts, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "last rollout 2 minutes ago (revision 4, image mysql:8)", latest.Summary(now))
+}
+
+func TestLatestOwned(t *testing.T) {
+	environment := newObject("workflow.skpr.io/v1beta1", "Environment", "dev", "environment", nil, now.Add(-48*time.Hour))
+	deployment := newObject("apps/v1", "Deployment", "dev-nginx", "deployment", environment, now.Add(-48*time.Hour))
+
+	f := newFinder(
+		environment,
+		deployment,
+		newReplicaSet(t, deployment, "dev-nginx-1", "2", "nginx:1.27", now.Add(-20*time.Second)),
+	)
+
+	latest, err := f.Latest(context.TODO(), environment, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "last rollout less than a minute ago (revision 2, image nginx:1.27)", latest.Summary(now))
+
+	latest, err = f.Latest(context.TODO(), newObject("v1", "Pod", "orphan", "pod", nil, now), now)
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+}
+
+func TestSessionSelector(t *testing.T) {
+	deployment := newObject("apps/v1", "Deployment", "drupal", "deployment", nil, now.Add(-48*time.Hour))
+	assert.NoError(t, unstructured.SetNestedStringMap(deployment.Object, map[string]string{"app": "drupal"}, "spec", "selector", "matchLabels"))
+
+	matching := newReplicaSet(t, deployment, "drupal-1", "1", "drupal:1.0", now.Add(-10*time.Minute))
+	matching.SetLabels(map[string]string{"app": "drupal"})
+
+	// Only ReplicaSets matching the Deployment's selector are listed.
+	other := newReplicaSet(t, deployment, "drupal-2", "2", "drupal:2.0", now.Add(-5*time.Minute))
+
+	client := newClient(deployment, matching, other)
+	session := New(client, time.Hour).Session()
+
+	for i := 0; i < 2; i++ {
+		latest, err := session.Latest(context.TODO(), deployment, now)
+		assert.NoError(t, err)
+		assert.Equal(t, "1", latest.Revision)
+	}
+
+	// The list is cached for the session.
+	assert.Len(t, client.Actions(), 1)
+	assert.Equal(t, "app=drupal", client.Actions()[0].(k8stesting.ListAction).GetListRestrictions().Labels.String())
+}
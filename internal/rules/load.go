@@ -0,0 +1,67 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"sigs.k8s.io/yaml"
+)
+
+// S3ClientInterface for loading rules from S3.
+type S3ClientInterface interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// Load a rules file from a local path or an s3://bucket/key URI.
+func Load(ctx context.Context, client S3ClientInterface, source string) (*File, error) {
+	data, err := read(ctx, client, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse a rules file in YAML or JSON format.
+func Parse(data []byte) (*File, error) {
+	var file File
+
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rules: %w", err)
+	}
+
+	if err := file.Compile(); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// read the raw rules from the source.
+func read(ctx context.Context, client S3ClientInterface, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "s3://") {
+		return os.ReadFile(source)
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse s3 uri: %w", err)
+	}
+
+	resp, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(u.Host),
+		Key:    aws.String(strings.TrimPrefix(u.Path, "/")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,262 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/cloudwatch"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+)
+
+// Precedence determines which source wins when both tags and a rule provide a value.
+type Precedence string
+
+const (
+	// PrecedenceTags means values from tags override values from rules.
+	PrecedenceTags Precedence = "tags"
+	// PrecedenceRules means values from rules override values from tags.
+	PrecedenceRules Precedence = "rules"
+)
+
+// File containing a list of rules which are evaluated in order.
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule which routes a matching alarm to a target.
+type Rule struct {
+	Name   string        `json:"name,omitempty"`
+	Match  Match         `json:"match"`
+	Target target.Target `json:"target"`
+
+	alarmName  *regexp.Regexp
+	logGroup   *regexp.Regexp
+	dimensions map[string]*regexp.Regexp
+}
+
+// Match conditions for a rule. All conditions which are set must match.
+type Match struct {
+	// AlarmName is a regular expression matched against the alarm name.
+	AlarmName string `json:"alarmName,omitempty"`
+	// AlarmARN must equal the alarm ARN.
+	AlarmARN string `json:"alarmArn,omitempty"`
+	// Namespace must equal the namespace of one of the alarm's metrics.
+	Namespace string `json:"namespace,omitempty"`
+	// Dimensions are regular expressions matched against the dimensions of one of the alarm's metrics.
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	// LogGroup is a regular expression matched against the log group of a CloudWatch Logs subscription.
+	LogGroup string `json:"logGroup,omitempty"`
+}
+
+// Alarm which is evaluated against the rules.
+type Alarm struct {
+	Name     string
+	ARN      string
+	Metrics  []Metric
+	LogGroup string
+}
+
+// Metric being evaluated by an alarm.
+type Metric struct {
+	Namespace  string
+	Dimensions map[string]string
+}
+
+// Compile the regular expressions for each rule.
+func (f *File) Compile() error {
+	for i := range f.Rules {
+		rule := &f.Rules[i]
+
+		if rule.Match.AlarmName != "" {
+			re, err := regexp.Compile(rule.Match.AlarmName)
+			if err != nil {
+				return fmt.Errorf("rule %d: failed to compile alarm name: %w", i, err)
+			}
+
+			rule.alarmName = re
+		}
+
+		if rule.Match.LogGroup != "" {
+			re, err := regexp.Compile(rule.Match.LogGroup)
+			if err != nil {
+				return fmt.Errorf("rule %d: failed to compile log group: %w", i, err)
+			}
+
+			rule.logGroup = re
+		}
+
+		rule.dimensions = make(map[string]*regexp.Regexp, len(rule.Match.Dimensions))
+
+		for key, value := range rule.Match.Dimensions {
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return fmt.Errorf("rule %d: failed to compile dimension %s: %w", i, key, err)
+			}
+
+			rule.dimensions[key] = re
+		}
+	}
+
+	return nil
+}
+
+// Evaluate the rules in order and return the target for the first rule which matches.
+func (f *File) Evaluate(alarm Alarm) (target.Target, bool) {
+	for _, rule := range f.Rules {
+		captures, ok := rule.match(alarm)
+		if !ok {
+			continue
+		}
+
+		return rule.expand(captures), true
+	}
+
+	return target.Target{}, false
+}
+
+// match the alarm against the rule, returning any captured values.
+func (r Rule) match(alarm Alarm) (map[string]string, bool) {
+	captures := make(map[string]string)
+
+	if r.Match.AlarmARN != "" && r.Match.AlarmARN != alarm.ARN {
+		return nil, false
+	}
+
+	if r.alarmName != nil {
+		if !capture(r.alarmName, alarm.Name, captures) {
+			return nil, false
+		}
+	}
+
+	if r.logGroup != nil {
+		groups := make(map[string]string)
+
+		if !capture(r.logGroup, alarm.LogGroup, groups) {
+			return nil, false
+		}
+
+		named(groups, captures)
+	}
+
+	if r.Match.Namespace == "" && len(r.dimensions) == 0 {
+		return captures, true
+	}
+
+	for _, metric := range alarm.Metrics {
+		if r.Match.Namespace != "" && r.Match.Namespace != metric.Namespace {
+			continue
+		}
+
+		dimensions := make(map[string]string)
+
+		if r.matchDimensions(metric, dimensions) {
+			named(dimensions, captures)
+			return captures, true
+		}
+	}
+
+	return nil, false
+}
+
+// matchDimensions checks all dimension conditions against a single metric.
+func (r Rule) matchDimensions(metric Metric, captures map[string]string) bool {
+	for key, re := range r.dimensions {
+		value, ok := metric.Dimensions[key]
+		if !ok {
+			return false
+		}
+
+		if !capture(re, value, captures) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// expand capture group references eg. $1 or ${project} in each of the target values.
+func (r Rule) expand(captures map[string]string) target.Target {
+	mapping := func(key string) string {
+		return captures[key]
+	}
+
+	return target.Target{
+		Cluster:    os.Expand(r.Target.Cluster, mapping),
+		APIGroup:   os.Expand(r.Target.APIGroup, mapping),
+		APIVersion: os.Expand(r.Target.APIVersion, mapping),
+		Kind:       os.Expand(r.Target.Kind, mapping),
+		Namespace:  os.Expand(r.Target.Namespace, mapping),
+		Name:       os.Expand(r.Target.Name, mapping),
+		Selector:   os.Expand(r.Target.Selector, mapping),
+		Reason:     os.Expand(r.Target.Reason, mapping),
+		Severity:   os.Expand(r.Target.Severity, mapping),
+		Owner:      os.Expand(r.Target.Owner, mapping),
+		OwnerMode:  os.Expand(r.Target.OwnerMode, mapping),
+		Sinks:      os.Expand(r.Target.Sinks, mapping),
+		Action:     os.Expand(r.Target.Action, mapping),
+	}
+}
+
+// capture matches the value and stores numbered and named capture groups.
+func capture(re *regexp.Regexp, value string, captures map[string]string) bool {
+	matches := re.FindStringSubmatch(value)
+	if matches == nil {
+		return false
+	}
+
+	for i, name := range re.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+
+		captures[strconv.Itoa(i)] = matches[i]
+
+		if name != "" {
+			captures[name] = matches[i]
+		}
+	}
+
+	return true
+}
+
+// named copies named capture groups. Numbered groups are reserved for the alarm name.
+func named(from, to map[string]string) {
+	for key, value := range from {
+		if _, err := strconv.Atoi(key); err == nil {
+			continue
+		}
+
+		to[key] = value
+	}
+}
+
+// Resolve the final target from tags and an optional rule using the given precedence.
+func Resolve(precedence Precedence, fromTags, fromRule target.Target) (target.Target, error) {
+	switch precedence {
+	case PrecedenceTags, "":
+		return fromTags.Merge(fromRule), nil
+	case PrecedenceRules:
+		return fromRule.Merge(fromTags), nil
+	}
+
+	return target.Target{}, fmt.Errorf("unknown precedence: %s", precedence)
+}
+
+// AlarmFromEvent converts a CloudWatch Alarm event into an alarm which can be evaluated.
+func AlarmFromEvent(event *cloudwatch.Event) Alarm {
+	alarm := Alarm{
+		Name: event.AlarmData.AlarmName,
+		ARN:  event.AlarmARN,
+	}
+
+	for _, metric := range event.AlarmData.Configuration.Metrics {
+		alarm.Metrics = append(alarm.Metrics, Metric{
+			Namespace:  metric.MetricStat.Metric.Namespace,
+			Dimensions: metric.MetricStat.Metric.Dimensions,
+		})
+	}
+
+	return alarm
+}
@@ -0,0 +1,118 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+)
+
+const testRules = `
+rules:
+- name: elb
+  match:
+    namespace: AWS/ApplicationELB
+    dimensions:
+      LoadBalancer: "^app/(?P<project>[a-z]+)-(?P<env>[a-z]+)/"
+  target:
+    cluster: skpr-prod
+    apiGroup: skpr.io
+    apiVersion: v1beta1
+    kind: Environment
+    namespace: skpr-project-${project}
+    name: ${env}
+    reason: LoadBalancerErrors
+- name: by-name
+  match:
+    alarmName: "^([a-z]+)-([a-z]+)-5xx$"
+  target:
+    cluster: skpr-dev
+    apiVersion: v1
+    kind: Pod
+    namespace: $1
+    name: $2
+    reason: HighErrorRate
+    severity: critical
+- name: logs
+  match:
+    logGroup: "^/skpr/(?P<project>[a-z]+)/(?P<env>[a-z]+)/php$"
+  target:
+    cluster: skpr-prod
+    apiVersion: v1
+    kind: Pod
+    namespace: skpr-project-${project}
+    name: ${env}
+    reason: PHPFatalError
+`
+
+func TestEvaluate(t *testing.T) {
+	file, err := Parse([]byte(testRules))
+	assert.NoError(t, err)
+
+	got, ok := file.Evaluate(Alarm{
+		Name: "drupal-prod-5xx",
+	})
+	assert.True(t, ok)
+	assert.Equal(t, target.Target{
+		Cluster:    "skpr-dev",
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  "drupal",
+		Name:       "prod",
+		Reason:     "HighErrorRate",
+		Severity:   "critical",
+	}, got)
+
+	got, ok = file.Evaluate(Alarm{
+		Name: "something-else",
+		Metrics: []Metric{
+			{
+				Namespace: "AWS/ApplicationELB",
+				Dimensions: map[string]string{
+					"LoadBalancer": "app/drupal-prod/123456",
+				},
+			},
+		},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "skpr-project-drupal", got.Namespace)
+	assert.Equal(t, "prod", got.Name)
+
+	got, ok = file.Evaluate(Alarm{
+		Name:     "php-fatal",
+		LogGroup: "/skpr/drupal/dev/php",
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "skpr-project-drupal", got.Namespace)
+	assert.Equal(t, "dev", got.Name)
+	assert.Equal(t, "PHPFatalError", got.Reason)
+
+	_, ok = file.Evaluate(Alarm{
+		Name: "unmatched",
+	})
+	assert.False(t, ok)
+}
+
+func TestResolve(t *testing.T) {
+	fromTags := target.Target{
+		Cluster: "from-tags",
+		Name:    "tagged",
+	}
+
+	fromRule := target.Target{
+		Cluster: "from-rule",
+		Kind:    "Pod",
+	}
+
+	got, err := Resolve(PrecedenceTags, fromTags, fromRule)
+	assert.NoError(t, err)
+	assert.Equal(t, target.Target{Cluster: "from-tags", Name: "tagged", Kind: "Pod"}, got)
+
+	got, err = Resolve(PrecedenceRules, fromTags, fromRule)
+	assert.NoError(t, err)
+	assert.Equal(t, target.Target{Cluster: "from-rule", Name: "tagged", Kind: "Pod"}, got)
+
+	_, err = Resolve("unknown", fromTags, fromRule)
+	assert.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package selftest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscloudwatch "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/cloudwatch"
+)
+
+const (
+	// CheckListTags confirms the alarm's tags can be listed.
+	CheckListTags = "cloudwatch:ListTagsForResource"
+	// CheckConnect confirms the cluster can be described and a token generated.
+	CheckConnect = "eks:DescribeCluster"
+	// CheckToken confirms the token is accepted by the cluster.
+	CheckToken = "kubernetes:SelfSubjectReview"
+	// CheckGetTarget confirms the target resource can be read.
+	CheckGetTarget = "kubernetes:get"
+	// CheckCreateEvents confirms events can be created.
+	CheckCreateEvents = "kubernetes:create events"
+)
+
+// Request to run a self-test.
+type Request struct {
+	// AlarmARN is an alarm which tags will be listed for. Optional.
+	AlarmARN string `json:"alarmArn,omitempty"`
+	// Cluster to connect to.
+	Cluster string `json:"cluster"`
+	// Namespace to check permissions in.
+	Namespace string `json:"namespace"`
+	// APIGroup of the target resource eg. workflow.skpr.io.
+	APIGroup string `json:"apiGroup,omitempty"`
+	// Resource is the plural name of the target resource eg. environments.
+	Resource string `json:"resource,omitempty"`
+}
+
+// Check is the result of a single permission check.
+type Check struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the result of all permission checks.
+type Report struct {
+	Passed bool    `json:"passed"`
+	Checks []Check `json:"checks"`
+}
+
+// ConnectFunc describes the cluster and returns a clientset for it.
+type ConnectFunc func(ctx context.Context, cluster string) (kubernetes.Interface, error)
+
+// Runner checks the permissions required by the forwarder.
+type Runner struct {
+	cloudwatch cloudwatch.ClientInterface
+	connect    ConnectFunc
+}
+
+// New creates a new runner.
+func New(cloudwatch cloudwatch.ClientInterface, connect ConnectFunc) *Runner {
+	return &Runner{
+		cloudwatch: cloudwatch,
+		connect:    connect,
+	}
+}
+
+// Run each check and return a report. Checks which depend on the cluster connection are skipped if it fails.
+func (r *Runner) Run(ctx context.Context, req Request) *Report {
+	report := &Report{}
+
+	if req.AlarmARN == "" {
+		report.skip(CheckListTags, "no alarm ARN provided")
+	} else {
+		_, err := r.cloudwatch.ListTagsForResource(ctx, &awscloudwatch.ListTagsForResourceInput{
+			ResourceARN: aws.String(req.AlarmARN),
+		})
+		report.add(CheckListTags, err)
+	}
+
+	clientset, err := r.connect(ctx, req.Cluster)
+	report.add(CheckConnect, err)
+
+	if err != nil {
+		report.skip(CheckToken, "cluster connection failed")
+		report.skip(CheckGetTarget, "cluster connection failed")
+		report.skip(CheckCreateEvents, "cluster connection failed")
+		report.finish()
+		return report
+	}
+
+	review, err := clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err == nil {
+		report.pass(CheckToken, fmt.Sprintf("authenticated as %s", review.Status.UserInfo.Username))
+	} else {
+		report.add(CheckToken, err)
+	}
+
+	if req.Resource == "" {
+		report.skip(CheckGetTarget, "no resource provided")
+	} else {
+		report.add(CheckGetTarget+" "+req.Resource, access(ctx, clientset, authorizationv1.ResourceAttributes{
+			Namespace: req.Namespace,
+			Verb:      "get",
+			Group:     req.APIGroup,
+			Resource:  req.Resource,
+		}))
+	}
+
+	report.add(CheckCreateEvents, access(ctx, clientset, authorizationv1.ResourceAttributes{
+		Namespace: req.Namespace,
+		Verb:      "create",
+		Resource:  "events",
+	}))
+
+	report.finish()
+
+	return report
+}
+
+// access checks if the current user is allowed to perform the action.
+func access(ctx context.Context, clientset kubernetes.Interface, attributes authorizationv1.ResourceAttributes) error {
+	review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &attributes,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	if !review.Status.Allowed {
+		return fmt.Errorf("not allowed to %s %s in namespace %s: %s", attributes.Verb, attributes.Resource, attributes.Namespace, review.Status.Reason)
+	}
+
+	return nil
+}
+
+// add a check which passed if there was no error.
+func (r *Report) add(name string, err error) {
+	if err != nil {
+		r.Checks = append(r.Checks, Check{Name: name, Message: err.Error()})
+		return
+	}
+
+	r.pass(name, "")
+}
+
+// pass adds a check which passed.
+func (r *Report) pass(name, message string) {
+	r.Checks = append(r.Checks, Check{Name: name, Passed: true, Message: message})
+}
+
+// skip adds a check which was not run.
+func (r *Report) skip(name, message string) {
+	r.Checks = append(r.Checks, Check{Name: name, Skipped: true, Message: message})
+}
+
+// finish determines if the report passed. Skipped checks do not fail the report.
+func (r *Report) finish() {
+	r.Passed = true
+
+	for _, check := range r.Checks {
+		if !check.Passed && !check.Skipped {
+			r.Passed = false
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/cloudwatch"
+)
+
+func newClientset(allowed map[string]bool) *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+
+	clientset.PrependReactor("create", "selfsubjectreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.SelfSubjectReview)
+		review.Status.UserInfo.Username = "lambda"
+		return true, review, nil
+	})
+
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = allowed[review.Spec.ResourceAttributes.Verb+" "+review.Spec.ResourceAttributes.Resource]
+		return true, review, nil
+	})
+
+	return clientset
+}
+
+func TestRun(t *testing.T) {
+	clientset := newClientset(map[string]bool{
+		"get environments": true,
+		"create events":    true,
+	})
+
+	runner := New(&cloudwatch.MockClient{}, func(ctx context.Context, cluster string) (kubernetes.Interface, error) {
+		return clientset, nil
+	})
+
+	report := runner.Run(context.TODO(), Request{
+		AlarmARN:  "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:test",
+		Cluster:   "test",
+		Namespace: "test",
+		APIGroup:  "workflow.skpr.io",
+		Resource:  "environments",
+	})
+
+	assert.True(t, report.Passed)
+	assert.Len(t, report.Checks, 5)
+	assert.Equal(t, "authenticated as lambda", report.Checks[2].Message)
+}
+
+func TestRunDenied(t *testing.T) {
+	clientset := newClientset(map[string]bool{
+		"get environments": true,
+	})
+
+	runner := New(&cloudwatch.MockClient{}, func(ctx context.Context, cluster string) (kubernetes.Interface, error) {
+		return clientset, nil
+	})
+
+	report := runner.Run(context.TODO(), Request{
+		Cluster:   "test",
+		Namespace: "test",
+		APIGroup:  "workflow.skpr.io",
+		Resource:  "environments",
+	})
+
+	assert.False(t, report.Passed)
+	assert.True(t, report.Checks[0].Skipped)
+	assert.False(t, report.Checks[4].Passed)
+}
+
+func TestRunConnectFailed(t *testing.T) {
+	runner := New(&cloudwatch.MockClient{}, func(ctx context.Context, cluster string) (kubernetes.Interface, error) {
+		return nil, fmt.Errorf("access denied")
+	})
+
+	report := runner.Run(context.TODO(), Request{
+		Cluster: "test",
+	})
+
+	assert.False(t, report.Passed)
+	assert.Equal(t, "access denied", report.Checks[1].Message)
+	assert.True(t, report.Checks[4].Skipped)
+}
@@ -0,0 +1,131 @@
+package severity
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Severity of an alarm.
+type Severity string
+
+const (
+	// Info is used for alarms which are informational eg. cost anomalies.
+	Info Severity = "info"
+	// Warning is used for alarms which need attention. This is the default.
+	Warning Severity = "warning"
+	// Critical is used for alarms which are page-worthy.
+	Critical Severity = "critical"
+)
+
+const (
+	// StateAlarm is the CloudWatch Alarm state when the threshold has been breached.
+	StateAlarm = "ALARM"
+	// StateOK is the CloudWatch Alarm state when the threshold has not been breached.
+	StateOK = "OK"
+	// StateInsufficientData is the CloudWatch Alarm state when there is not enough data to evaluate.
+	StateInsufficientData = "INSUFFICIENT_DATA"
+)
+
+// Wildcard matches any severity or state in a policy rule.
+const Wildcard = "*"
+
+// Parse a severity, defaulting to Warning when empty.
+func Parse(value string) (Severity, error) {
+	switch Severity(strings.ToLower(value)) {
+	case "":
+		return Warning, nil
+	case Info:
+		return Info, nil
+	case Warning:
+		return Warning, nil
+	case Critical:
+		return Critical, nil
+	}
+
+	return "", fmt.Errorf("unknown severity: %s", value)
+}
+
+// Rule which maps a severity and alarm state to an event type.
+type Rule struct {
+	Severity string
+	State    string
+	Type     string
+}
+
+// Policy is a list of rules which are evaluated in order.
+type Policy []Rule
+
+// DefaultPolicy is evaluated after any configured rules.
+var DefaultPolicy = Policy{
+	{Severity: Wildcard, State: StateOK, Type: corev1.EventTypeNormal},
+	{Severity: Wildcard, State: StateInsufficientData, Type: corev1.EventTypeNormal},
+	{Severity: string(Info), State: Wildcard, Type: corev1.EventTypeNormal},
+	{Severity: Wildcard, State: Wildcard, Type: corev1.EventTypeWarning},
+}
+
+// ParsePolicy parses a comma separated list of rules in the format severity:state=Type eg. "info:ALARM=Warning,*:OK=Normal".
+func ParsePolicy(value string) (Policy, error) {
+	var policy Policy
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		match, eventType, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule: %s", item)
+		}
+
+		sev, state, ok := strings.Cut(match, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule: %s", item)
+		}
+
+		sev, state = strings.ToLower(sev), strings.ToUpper(state)
+
+		if _, err := Parse(sev); sev != Wildcard && (sev == "" || err != nil) {
+			return nil, fmt.Errorf("invalid severity in rule %s: %s", item, sev)
+		}
+
+		switch state {
+		case Wildcard, StateAlarm, StateOK, StateInsufficientData:
+		default:
+			return nil, fmt.Errorf("invalid state in rule %s: %s", item, state)
+		}
+
+		if eventType != corev1.EventTypeNormal && eventType != corev1.EventTypeWarning {
+			return nil, fmt.Errorf("invalid event type: %s", eventType)
+		}
+
+		policy = append(policy, Rule{
+			Severity: sev,
+			State:    state,
+			Type:     eventType,
+		})
+	}
+
+	return policy, nil
+}
+
+// EventType returns the event type for the first rule which matches, falling back to the default policy.
+func (p Policy) EventType(sev Severity, state string) string {
+	for _, policy := range []Policy{p, DefaultPolicy} {
+		for _, rule := range policy {
+			if rule.Severity != Wildcard && rule.Severity != string(sev) {
+				continue
+			}
+
+			if rule.State != Wildcard && rule.State != state {
+				continue
+			}
+
+			return rule.Type
+		}
+	}
+
+	return corev1.EventTypeWarning
+}
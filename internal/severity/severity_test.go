@@ -0,0 +1,52 @@
+package severity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParse(t *testing.T) {
+	sev, err := Parse("")
+	assert.NoError(t, err)
+	assert.Equal(t, Warning, sev)
+
+	sev, err = Parse("Critical")
+	assert.NoError(t, err)
+	assert.Equal(t, Critical, sev)
+
+	_, err = Parse("urgent")
+	assert.Error(t, err)
+}
+
+func TestEventType(t *testing.T) {
+	var policy Policy
+
+	assert.Equal(t, corev1.EventTypeWarning, policy.EventType(Warning, StateAlarm))
+	assert.Equal(t, corev1.EventTypeWarning, policy.EventType(Critical, StateAlarm))
+	assert.Equal(t, corev1.EventTypeNormal, policy.EventType(Info, StateAlarm))
+	assert.Equal(t, corev1.EventTypeNormal, policy.EventType(Critical, StateOK))
+
+	policy, err := ParsePolicy("info:ALARM=Warning, *:INSUFFICIENT_DATA=Warning")
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.EventTypeWarning, policy.EventType(Info, StateAlarm))
+	assert.Equal(t, corev1.EventTypeWarning, policy.EventType(Warning, StateInsufficientData))
+	assert.Equal(t, corev1.EventTypeNormal, policy.EventType(Info, StateOK))
+
+	_, err = ParsePolicy("info:ALARM=Error")
+	assert.Error(t, err)
+
+	_, err = ParsePolicy("info=Warning")
+	assert.Error(t, err)
+
+	// Typos are rejected rather than never matching.
+	_, err = ParsePolicy("*:ALRAM=Warning")
+	assert.ErrorContains(t, err, "invalid state")
+
+	_, err = ParsePolicy("critcal:ALARM=Warning")
+	assert.ErrorContains(t, err, "invalid severity")
+
+	_, err = ParsePolicy(":ALARM=Warning")
+	assert.ErrorContains(t, err, "invalid severity")
+}
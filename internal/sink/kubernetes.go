@@ -0,0 +1,76 @@
+package sink
+
+import (
+	"context"
+	"sync"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/forwarder"
+)
+
+// ConnectFunc connects to the target cluster and returns a forwarder for it.
+type ConnectFunc func(ctx context.Context) (*forwarder.Forwarder, error)
+
+// Kubernetes creates events on the target Kubernetes objects.
+type Kubernetes struct {
+	connect ConnectFunc
+}
+
+// NewKubernetes creates a sink which connects to the cluster when the alarm is sent, so connection failures are
+// isolated from other sinks.
+func NewKubernetes(connect ConnectFunc) *Kubernetes {
+	return &Kubernetes{
+		connect: connect,
+	}
+}
+
+// Send the alarm to the cluster as events.
+func (k *Kubernetes) Send(ctx context.Context, alarm forwarder.Alarm) error {
+	f, err := k.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	return f.Forward(ctx, alarm)
+}
+
+// Connections caches forwarders by cluster so each cluster is connected to once, even when alarms for it are sent
+// concurrently.
+type Connections struct {
+	connect  func(ctx context.Context, cluster string) (*forwarder.Forwarder, error)
+	mu       sync.Mutex
+	clusters map[string]*connection
+}
+
+// connection to a single cluster.
+type connection struct {
+	once      sync.Once
+	forwarder *forwarder.Forwarder
+	err       error
+}
+
+// NewConnections creates a cache which uses connect to set up each cluster connection.
+func NewConnections(connect func(ctx context.Context, cluster string) (*forwarder.Forwarder, error)) *Connections {
+	return &Connections{
+		connect:  connect,
+		clusters: make(map[string]*connection),
+	}
+}
+
+// Connect returns a function which connects to the cluster, reusing an existing connection.
+func (c *Connections) Connect(cluster string) ConnectFunc {
+	return func(ctx context.Context) (*forwarder.Forwarder, error) {
+		c.mu.Lock()
+		conn, ok := c.clusters[cluster]
+		if !ok {
+			conn = &connection{}
+			c.clusters[cluster] = conn
+		}
+		c.mu.Unlock()
+
+		conn.once.Do(func() {
+			conn.forwarder, conn.err = c.connect(ctx, cluster)
+		})
+
+		return conn.forwarder, conn.err
+	}
+}
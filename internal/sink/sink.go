@@ -0,0 +1,125 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/deadline"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/forwarder"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/metrics"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/report"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/tracing"
+)
+
+const (
+	// NameKubernetes creates events on the target Kubernetes objects.
+	NameKubernetes = "kubernetes"
+	// NameWebhook posts a signed JSON payload to a webhook.
+	NameWebhook = "webhook"
+	// NameSlack posts a message to a Slack incoming webhook.
+	NameSlack = "slack"
+	// NameStdout writes a JSON payload to stdout.
+	NameStdout = "stdout"
+)
+
+// Sink delivers an alarm to a destination.
+type Sink interface {
+	Send(ctx context.Context, alarm forwarder.Alarm) error
+}
+
+// WithTimeout wraps the sink so each send is given up after the timeout, or the parent's deadline if it is sooner, so a
+// hung endpoint cannot use the rest of the invocation.
+func WithTimeout(s Sink, timeout time.Duration) Sink {
+	return &timeoutSink{
+		sink:    s,
+		timeout: timeout,
+	}
+}
+
+type timeoutSink struct {
+	sink    Sink
+	timeout time.Duration
+}
+
+// Send the alarm within the stage deadline.
+func (t *timeoutSink) Send(ctx context.Context, alarm forwarder.Alarm) error {
+	ctx, cancel := deadline.Stage(ctx, t.timeout)
+	defer cancel()
+
+	return t.sink.Send(ctx, alarm)
+}
+
+// Parse a comma separated list of sink names. Kubernetes is used if no sinks are provided.
+func Parse(value string) []string {
+	var names []string
+
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return []string{NameKubernetes}
+	}
+
+	return names
+}
+
+// Failed logs, counts and reports a sink which did not deliver the alarm.
+func Failed(ctx context.Context, name string, err error) {
+	logging.FromContext(ctx).Error("Sink failed", "sink", name, "error_class", metrics.Classify(err), "error", err.Error())
+	metrics.FromContext(ctx).Add(metrics.SinkFailed, 1)
+	report.FromContext(ctx).SinkFailed(name, err)
+}
+
+// Required sinks fail the record when they do not deliver the alarm, so it is retried rather than lost. The other sinks
+// are sent the same delivery ID on retry so receivers can discard duplicates.
+var Required = map[string]bool{
+	NameKubernetes: true,
+}
+
+// Fanout sends the alarm to each sink. A failing sink does not stop the others, and each failure is reported separately.
+// An error is returned when a required sink failed or no sink delivered the alarm, so the record is retried.
+func Fanout(ctx context.Context, alarm forwarder.Alarm, sinks map[string]Sink) error {
+	var (
+		logger    = logging.FromContext(ctx)
+		names     = make([]string, 0, len(sinks))
+		errs      []error
+		required  bool
+		delivered int
+	)
+
+	for name := range sinks {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		spanCtx, span := tracing.Start(ctx, "Sink "+name)
+
+		err := sinks[name].Send(logging.With(spanCtx, "sink", name), alarm)
+		tracing.End(span, err)
+		if err != nil {
+			Failed(ctx, name, err)
+			errs = append(errs, fmt.Errorf("sink %s: %w", name, err))
+			required = required || Required[name]
+			continue
+		}
+
+		logger.Info("Sent alarm to sink", "sink", name)
+		delivered++
+	}
+
+	if delivered > 0 && !required {
+		return nil
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,208 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/forwarder"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/report"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/severity"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+)
+
+func newAlarm() forwarder.Alarm {
+	return forwarder.Alarm{
+		Name:        "drupal-5xx",
+		ARN:         "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:drupal-5xx",
+		State:       severity.StateAlarm,
+		Description: "This is a test",
+		Severity:    severity.Warning,
+		Target: target.Target{
+			Cluster:   "test",
+			Kind:      "Pod",
+			Namespace: "test",
+			Name:      "drupal",
+		},
+		Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+type mockSink struct {
+	err   error
+	calls int
+}
+
+func (m *mockSink) Send(ctx context.Context, alarm forwarder.Alarm) error {
+	m.calls++
+	return m.err
+}
+
+func TestParse(t *testing.T) {
+	assert.Equal(t, []string{NameKubernetes}, Parse(""))
+	assert.Equal(t, []string{NameKubernetes, NameSlack}, Parse("kubernetes, slack"))
+}
+
+func TestFanout(t *testing.T) {
+	var (
+		failing = &mockSink{err: fmt.Errorf("connection refused")}
+		working = &mockSink{}
+	)
+
+	rep := &report.Target{}
+
+	// The alarm was delivered by one sink, so the failure of an optional sink is reported without failing the record.
+	err := Fanout(report.WithContext(context.TODO(), rep), newAlarm(), map[string]Sink{
+		NameWebhook: failing,
+		NameStdout:  working,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, failing.calls)
+	assert.Equal(t, 1, working.calls)
+	assert.Len(t, rep.SinkFailures, 1)
+	assert.Equal(t, NameWebhook, rep.SinkFailures[0].Sink)
+
+	// A required sink which fails is retried even though another sink delivered the alarm.
+	rep = &report.Target{}
+
+	err = Fanout(report.WithContext(context.TODO(), rep), newAlarm(), map[string]Sink{
+		NameKubernetes: failing,
+		NameStdout:     working,
+	})
+	assert.ErrorContains(t, err, "sink kubernetes: connection refused")
+	assert.Equal(t, 2, working.calls)
+	assert.Len(t, rep.SinkFailures, 1)
+
+	err = Fanout(context.TODO(), newAlarm(), map[string]Sink{NameWebhook: failing})
+	assert.ErrorContains(t, err, "sink webhook: connection refused")
+
+	assert.NoError(t, Fanout(context.TODO(), newAlarm(), map[string]Sink{NameStdout: working}))
+}
+
+type blockingSink struct{}
+
+func (blockingSink) Send(ctx context.Context, alarm forwarder.Alarm) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWithTimeout(t *testing.T) {
+	err := WithTimeout(blockingSink{}, 10*time.Millisecond).Send(context.TODO(), newAlarm())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.NoError(t, WithTimeout(&mockSink{}, time.Second).Send(context.TODO(), newAlarm()))
+}
+
+func TestKubernetesConnectFailed(t *testing.T) {
+	k := NewKubernetes(func(ctx context.Context) (*forwarder.Forwarder, error) {
+		return nil, fmt.Errorf("access denied")
+	})
+
+	assert.ErrorContains(t, k.Send(context.TODO(), newAlarm()), "access denied")
+}
+
+func TestConnections(t *testing.T) {
+	calls := make(map[string]int)
+
+	connections := NewConnections(func(ctx context.Context, cluster string) (*forwarder.Forwarder, error) {
+		calls[cluster]++
+		return nil, fmt.Errorf("access denied to %s", cluster)
+	})
+
+	for _, cluster := range []string{"a", "a", "b"} {
+		_, err := connections.Connect(cluster)(context.TODO())
+		assert.ErrorContains(t, err, cluster)
+	}
+
+	assert.Equal(t, map[string]int{"a": 1, "b": 1}, calls)
+}
+
+func TestWebhook(t *testing.T) {
+	var (
+		body      []byte
+		signature string
+		keys      []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		keys = append(keys, r.Header.Get(IdempotencyHeader))
+	}))
+	defer server.Close()
+
+	w, err := NewWebhook(server.Client(), server.URL, "secret")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Send(context.TODO(), newAlarm()))
+
+	assert.Equal(t, Sign("secret", body), signature)
+
+	var payload Payload
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "drupal-5xx", payload.AlarmName)
+	assert.Equal(t, "drupal", payload.Target.Name)
+	assert.Nil(t, payload.PreviousTimestamp)
+
+	// A retry is sent with the same delivery ID so the receiver can discard it.
+	assert.NoError(t, w.Send(context.TODO(), newAlarm()))
+	assert.Len(t, keys, 2)
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, payload.DeliveryID, keys[0])
+
+	recovery := newAlarm()
+	recovery.State = severity.StateOK
+	assert.NotEqual(t, keys[0], DeliveryID(recovery))
+}
+
+func TestWebhookFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	w, err := NewWebhook(server.Client(), server.URL, "")
+	assert.NoError(t, err)
+	assert.ErrorContains(t, w.Send(context.TODO(), newAlarm()), "unexpected status 500")
+
+	_, err = NewWebhook(server.Client(), "", "")
+	assert.Error(t, err)
+}
+
+func TestSlack(t *testing.T) {
+	var message SlackMessage
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+	}))
+	defer server.Close()
+
+	s, err := NewSlack(server.Client(), server.URL)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Send(context.TODO(), newAlarm()))
+
+	assert.Equal(t, "CloudWatch Alarm *drupal-5xx* is ALARM", message.Text)
+	assert.Len(t, message.Attachments, 1)
+	assert.Equal(t, colourAlarm, message.Attachments[0].Color)
+	assert.Equal(t, "This is a test", message.Attachments[0].Text)
+	assert.Contains(t, message.Attachments[0].Fields, SlackField{Title: "Target", Value: "Pod test/drupal", Short: true})
+}
+
+func TestStdout(t *testing.T) {
+	var buf bytes.Buffer
+
+	assert.NoError(t, NewStdout(&buf).Send(context.TODO(), newAlarm()))
+
+	var payload Payload
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &payload))
+	assert.Equal(t, severity.StateAlarm, payload.State)
+	assert.Equal(t, "warning", payload.Severity)
+}
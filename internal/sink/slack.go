@@ -0,0 +1,109 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/forwarder"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/severity"
+)
+
+// Colours used for Slack attachments.
+const (
+	colourAlarm = "danger"
+	colourOK    = "good"
+	colourOther = "warning"
+)
+
+// SlackMessage is the body of a Slack incoming webhook.
+type SlackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []SlackAttachment `json:"attachments"`
+}
+
+// SlackAttachment is a Slack message attachment.
+type SlackAttachment struct {
+	Color  string       `json:"color"`
+	Title  string       `json:"title"`
+	Text   string       `json:"text"`
+	Fields []SlackField `json:"fields"`
+	Ts     int64        `json:"ts,omitempty"`
+}
+
+// SlackField is a short piece of information in an attachment.
+type SlackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// Slack posts a formatted message to a Slack incoming webhook.
+type Slack struct {
+	client *http.Client
+	url    string
+}
+
+// NewSlack creates a new Slack sink.
+func NewSlack(client *http.Client, url string) (*Slack, error) {
+	if url == "" {
+		return nil, fmt.Errorf("slack webhook url is required")
+	}
+
+	return &Slack{
+		client: client,
+		url:    url,
+	}, nil
+}
+
+// Send the alarm to Slack.
+func (s *Slack) Send(ctx context.Context, alarm forwarder.Alarm) error {
+	body, err := json.Marshal(NewSlackMessage(alarm))
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	return post(ctx, s.client, s.url, body, nil)
+}
+
+// NewSlackMessage formats the alarm as a Slack message.
+func NewSlackMessage(alarm forwarder.Alarm) SlackMessage {
+	colour := colourOther
+
+	switch alarm.State {
+	case severity.StateAlarm:
+		colour = colourAlarm
+	case severity.StateOK:
+		colour = colourOK
+	}
+
+	t := alarm.Target
+
+	attachment := SlackAttachment{
+		Color: colour,
+		Title: alarm.Name,
+		Text:  alarm.Description,
+		Fields: []SlackField{
+			{Title: "State", Value: alarm.State, Short: true},
+			{Title: "Severity", Value: string(alarm.Severity), Short: true},
+		},
+	}
+
+	if t.Cluster != "" {
+		attachment.Fields = append(attachment.Fields, SlackField{Title: "Cluster", Value: t.Cluster, Short: true})
+	}
+
+	if t.Kind != "" {
+		attachment.Fields = append(attachment.Fields, SlackField{Title: "Target", Value: fmt.Sprintf("%s %s/%s%s", t.Kind, t.Namespace, t.Name, t.Selector), Short: true})
+	}
+
+	if !alarm.Timestamp.IsZero() {
+		attachment.Ts = alarm.Timestamp.Unix()
+	}
+
+	return SlackMessage{
+		Text:        fmt.Sprintf("CloudWatch Alarm *%s* is %s", alarm.Name, alarm.State),
+		Attachments: []SlackAttachment{attachment},
+	}
+}
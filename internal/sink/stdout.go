@@ -0,0 +1,34 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/forwarder"
+)
+
+// Stdout writes the alarm as a JSON line.
+type Stdout struct {
+	writer io.Writer
+}
+
+// NewStdout creates a new sink which writes to the writer.
+func NewStdout(w io.Writer) *Stdout {
+	return &Stdout{
+		writer: w,
+	}
+}
+
+// Send the alarm to the writer.
+func (s *Stdout) Send(ctx context.Context, alarm forwarder.Alarm) error {
+	data, err := json.Marshal(NewPayload(alarm))
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	_, err = fmt.Fprintln(s.writer, string(data))
+
+	return err
+}
@@ -0,0 +1,148 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/forwarder"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+)
+
+const (
+	// SignatureHeader is the header which contains the HMAC SHA256 signature of the webhook body.
+	SignatureHeader = "X-Signature-256"
+	// IdempotencyHeader is the header which contains the delivery ID, which is the same when a record is retried.
+	IdempotencyHeader = "Idempotency-Key"
+)
+
+// Payload is the JSON representation of an alarm sent to webhooks and stdout.
+type Payload struct {
+	DeliveryID        string            `json:"deliveryId"`
+	AlarmName         string            `json:"alarmName"`
+	AlarmARN          string            `json:"alarmArn"`
+	State             string            `json:"state"`
+	Description       string            `json:"description"`
+	Severity          string            `json:"severity"`
+	Target            target.Target     `json:"target"`
+	Timestamp         time.Time         `json:"timestamp"`
+	PreviousTimestamp *time.Time        `json:"previousTimestamp,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+}
+
+// NewPayload from an alarm.
+func NewPayload(alarm forwarder.Alarm) Payload {
+	payload := Payload{
+		DeliveryID:  DeliveryID(alarm),
+		AlarmName:   alarm.Name,
+		AlarmARN:    alarm.ARN,
+		State:       alarm.State,
+		Description: alarm.Description,
+		Severity:    string(alarm.Severity),
+		Target:      alarm.Target,
+		Timestamp:   alarm.Timestamp,
+		Annotations: alarm.Annotations,
+	}
+
+	if !alarm.PreviousTimestamp.IsZero() {
+		payload.PreviousTimestamp = &alarm.PreviousTimestamp
+	}
+
+	return payload
+}
+
+// DeliveryID identifies the alarm state change, so it is the same each time a record is retried.
+func DeliveryID(alarm forwarder.Alarm) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		alarm.ARN,
+		alarm.Name,
+		alarm.State,
+		alarm.Timestamp.UTC().Format(time.RFC3339Nano),
+	}, "\n")))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// Webhook posts a JSON payload to a URL, signed using HMAC SHA256 when a secret is configured.
+type Webhook struct {
+	client *http.Client
+	url    string
+	secret string
+}
+
+// NewWebhook creates a new webhook sink.
+func NewWebhook(client *http.Client, url, secret string) (*Webhook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+
+	return &Webhook{
+		client: client,
+		url:    url,
+		secret: secret,
+	}, nil
+}
+
+// Send the alarm to the webhook.
+func (w *Webhook) Send(ctx context.Context, alarm forwarder.Alarm) error {
+	payload := NewPayload(alarm)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	headers := map[string]string{
+		IdempotencyHeader: payload.DeliveryID,
+	}
+
+	if w.secret != "" {
+		headers[SignatureHeader] = Sign(w.secret, body)
+	}
+
+	return post(ctx, w.client, w.url, body, headers)
+}
+
+// Sign the body using HMAC SHA256, in the format "sha256=<hex>".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// post a JSON body to the URL and check the response status.
+func post(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, data)
+	}
+
+	return nil
+}
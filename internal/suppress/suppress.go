@@ -0,0 +1,115 @@
+package suppress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+// Window is a recurring period where events are suppressed.
+type Window struct {
+	// Namespace is a glob which the target namespace must match. Empty matches all namespaces.
+	Namespace string `json:"namespace,omitempty"`
+	// Schedule is a cron expression for when the window starts.
+	Schedule string `json:"schedule"`
+	// Duration of the window eg. 2h.
+	Duration string `json:"duration"`
+
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// Checker determines if events should be suppressed.
+type Checker struct {
+	windows []Window
+	now     func() time.Time
+}
+
+// New creates a checker from a JSON list of windows.
+func New(windows string) (*Checker, error) {
+	checker := &Checker{
+		now: time.Now,
+	}
+
+	if windows == "" {
+		return checker, nil
+	}
+
+	if err := json.Unmarshal([]byte(windows), &checker.windows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal windows: %w", err)
+	}
+
+	for i := range checker.windows {
+		window := &checker.windows[i]
+
+		schedule, err := cron.ParseStandard(window.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: failed to parse schedule: %w", i, err)
+		}
+
+		duration, err := time.ParseDuration(window.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: failed to parse duration: %w", i, err)
+		}
+
+		window.schedule = schedule
+		window.duration = duration
+	}
+
+	return checker, nil
+}
+
+// Window returns a reason if the namespace is within a suppression window.
+func (c *Checker) Window(namespace string) (string, bool) {
+	now := c.now()
+
+	for _, window := range c.windows {
+		if window.Namespace != "" {
+			if ok, _ := path.Match(window.Namespace, namespace); !ok {
+				continue
+			}
+		}
+
+		// The window is active if it was triggered within the last duration.
+		if !window.schedule.Next(now.Add(-window.duration)).After(now) {
+			return fmt.Sprintf("suppression window %q for %s", window.Schedule, window.Duration), true
+		}
+	}
+
+	return "", false
+}
+
+// Muted returns a reason if the object has been annotated to mute the alarm. A muted-until time which cannot be parsed
+// does not mute the alarm, and is logged so the annotation can be fixed.
+func (c *Checker) Muted(ctx context.Context, object metav1.Object, alarmName string) (string, bool) {
+	annotations := object.GetAnnotations()
+
+	if value, ok := annotations[annotation.KeyMutedUntil]; ok {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Ignoring invalid muted until annotation", "namespace", object.GetNamespace(), "name", object.GetName(),
+				"annotation", annotation.KeyMutedUntil, "value", value, "error", err.Error())
+		} else if c.now().Before(until) {
+			return fmt.Sprintf("%s muted until %s", object.GetName(), value), true
+		}
+	}
+
+	if value, ok := annotations[annotation.KeyMutedAlarms]; ok {
+		for _, name := range strings.Split(value, ",") {
+			if strings.TrimSpace(name) == alarmName {
+				return fmt.Sprintf("%s muted alarm %s", object.GetName(), alarmName), true
+			}
+		}
+	}
+
+	return "", false
+}
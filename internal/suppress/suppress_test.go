@@ -0,0 +1,95 @@
+package suppress
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
+)
+
+func TestWindow(t *testing.T) {
+	checker, err := New(`[{"namespace": "skpr-project-*", "schedule": "0 2 * * *", "duration": "2h"}]`)
+	assert.NoError(t, err)
+
+	checker.now = func() time.Time {
+		return time.Date(2024, 1, 1, 3, 0, 0, 0, time.Local)
+	}
+
+	_, ok := checker.Window("skpr-project-drupal")
+	assert.True(t, ok)
+
+	_, ok = checker.Window("kube-system")
+	assert.False(t, ok)
+
+	checker.now = func() time.Time {
+		return time.Date(2024, 1, 1, 4, 30, 0, 0, time.Local)
+	}
+
+	_, ok = checker.Window("skpr-project-drupal")
+	assert.False(t, ok)
+
+	_, err = New(`[{"schedule": "not a schedule", "duration": "2h"}]`)
+	assert.Error(t, err)
+}
+
+func TestMuted(t *testing.T) {
+	checker, err := New("")
+	assert.NoError(t, err)
+
+	checker.now = func() time.Time {
+		return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	object := &metav1.ObjectMeta{
+		Name: "prod",
+		Annotations: map[string]string{
+			annotation.KeyMutedUntil: "2024-01-01T01:00:00Z",
+		},
+	}
+
+	_, ok := checker.Muted(context.TODO(), object, "foo")
+	assert.True(t, ok)
+
+	object.Annotations[annotation.KeyMutedUntil] = "2023-12-31T23:00:00Z"
+
+	_, ok = checker.Muted(context.TODO(), object, "foo")
+	assert.False(t, ok)
+
+	object.Annotations[annotation.KeyMutedAlarms] = "bar, foo"
+
+	_, ok = checker.Muted(context.TODO(), object, "foo")
+	assert.True(t, ok)
+
+	_, ok = checker.Muted(context.TODO(), object, "baz")
+	assert.False(t, ok)
+}
+
+func TestMutedInvalid(t *testing.T) {
+	checker, err := New("")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	ctx := logging.WithContext(context.TODO(), logging.New(&buf))
+
+	object := &metav1.ObjectMeta{
+		Namespace: "skpr-project",
+		Name:      "prod",
+		Annotations: map[string]string{
+			annotation.KeyMutedUntil: "tomorrow",
+		},
+	}
+
+	_, ok := checker.Muted(ctx, object, "foo")
+	assert.False(t, ok)
+
+	assert.Contains(t, buf.String(), "Ignoring invalid muted until annotation")
+	assert.Contains(t, buf.String(), `"namespace":"skpr-project"`)
+	assert.Contains(t, buf.String(), `"value":"tomorrow"`)
+}
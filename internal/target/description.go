@@ -0,0 +1,111 @@
+package target
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// FrontMatterDelimiter opens and closes a YAML block at the start of an alarm description.
+	FrontMatterDelimiter = "---"
+	// TrailerPrefix marks a "key=value" line in an alarm description eg. "skpr:namespace=skpr-project".
+	TrailerPrefix = "skpr:"
+
+	// DescriptionPrecedenceTags uses the description to fill in fields which are not set by tags.
+	DescriptionPrecedenceTags = "tags"
+	// DescriptionPrecedenceDescription uses the description in place of fields set by tags.
+	DescriptionPrecedenceDescription = "description"
+)
+
+// FromDescription extracts routing fields from YAML front matter and trailing "skpr:" lines in an alarm description.
+// The remaining description is returned as the message.
+func FromDescription(description string) (Target, string, error) {
+	var (
+		t     Target
+		lines = strings.Split(strings.ReplaceAll(description, "\r\n", "\n"), "\n")
+	)
+
+	if len(lines) > 1 && lines[0] == FrontMatterDelimiter {
+		end := slices.Index(lines[1:], FrontMatterDelimiter)
+		if end < 0 {
+			return Target{}, "", fmt.Errorf("front matter is not closed")
+		}
+
+		if err := yaml.UnmarshalStrict([]byte(strings.Join(lines[1:end+1], "\n")), &t); err != nil {
+			return Target{}, "", fmt.Errorf("failed to parse front matter: %w", err)
+		}
+
+		lines = lines[end+2:]
+	}
+
+	// Only the trailing block of "key=value" lines are trailers, so "skpr:" lines elsewhere remain in the message.
+	var (
+		fields = make(map[string]string)
+		end    = len(lines)
+	)
+
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+
+	for ; end > 0; end-- {
+		field, ok := strings.CutPrefix(strings.TrimSpace(lines[end-1]), TrailerPrefix)
+		if !ok {
+			break
+		}
+
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			break
+		}
+
+		fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	if len(fields) > 0 {
+		trailer, err := fromFields(fields)
+		if err != nil {
+			return Target{}, "", err
+		}
+
+		t = trailer.Merge(t)
+	}
+
+	return t, strings.TrimSpace(strings.Join(lines[:end], "\n")), nil
+}
+
+// fromFields builds a target from fields keyed by their JSON name, rejecting unknown fields.
+func fromFields(fields map[string]string) (Target, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return Target{}, fmt.Errorf("failed to marshal trailer: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var t Target
+
+	if err := decoder.Decode(&t); err != nil {
+		return Target{}, fmt.Errorf("failed to parse trailer: %w", err)
+	}
+
+	return t, nil
+}
+
+// WithDescription combines the targets from tags and the description using the precedence.
+func WithDescription(precedence string, fromTags, fromDescription Target) (Target, error) {
+	switch precedence {
+	case DescriptionPrecedenceTags, "":
+		return fromTags.Merge(fromDescription), nil
+	case DescriptionPrecedenceDescription:
+		return fromDescription.Merge(fromTags), nil
+	}
+
+	return Target{}, fmt.Errorf("unknown description precedence: %s", precedence)
+}
@@ -0,0 +1,69 @@
+package target
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromDescription(t *testing.T) {
+	got, message, err := FromDescription("---\ncluster: skpr-prod\nkind: Environment\nname: prod\n---\nHigh error rate on the load balancer")
+	assert.NoError(t, err)
+	assert.Equal(t, Target{Cluster: "skpr-prod", Kind: "Environment", Name: "prod"}, got)
+	assert.Equal(t, "High error rate on the load balancer", message)
+
+	got, message, err = FromDescription("High error rate\nskpr:namespace=skpr-project\nskpr: reason = HighErrorRate")
+	assert.NoError(t, err)
+	assert.Equal(t, Target{Namespace: "skpr-project", Reason: "HighErrorRate"}, got)
+	assert.Equal(t, "High error rate", message)
+
+	got, message, err = FromDescription("Plain description")
+	assert.NoError(t, err)
+	assert.Equal(t, Target{}, got)
+	assert.Equal(t, "Plain description", message)
+
+	_, _, err = FromDescription("---\nname: prod\n")
+	assert.Error(t, err)
+
+	_, _, err = FromDescription("---\nunknown: prod\n---\n")
+	assert.Error(t, err)
+
+	_, _, err = FromDescription("skpr:unknown=prod")
+	assert.Error(t, err)
+
+	// Lines which are not part of the trailing block remain in the message.
+	got, message, err = FromDescription("skpr: high CPU on web\nskpr:namespace=first\nCheck the dashboard\nskpr:name=prod\n")
+	assert.NoError(t, err)
+	assert.Equal(t, Target{Name: "prod"}, got)
+	assert.Equal(t, "skpr: high CPU on web\nskpr:namespace=first\nCheck the dashboard", message)
+
+	got, message, err = FromDescription("High CPU\nskpr: high CPU on web")
+	assert.NoError(t, err)
+	assert.Equal(t, Target{}, got)
+	assert.Equal(t, "High CPU\nskpr: high CPU on web", message)
+
+	got, message, err = FromDescription("---\r\nname: prod\r\n---\r\nHigh CPU\r\nskpr:namespace=skpr-project\r\n")
+	assert.NoError(t, err)
+	assert.Equal(t, Target{Namespace: "skpr-project", Name: "prod"}, got)
+	assert.Equal(t, "High CPU", message)
+
+	// The front matter is only closed by a line which is exactly the delimiter.
+	_, _, err = FromDescription("---\nname: prod\n----\nHigh CPU")
+	assert.ErrorContains(t, err, "front matter is not closed")
+}
+
+func TestWithDescription(t *testing.T) {
+	fromTags := Target{Name: "from-tags"}
+	fromDescription := Target{Name: "from-description", Namespace: "skpr-project"}
+
+	got, err := WithDescription(DescriptionPrecedenceTags, fromTags, fromDescription)
+	assert.NoError(t, err)
+	assert.Equal(t, Target{Name: "from-tags", Namespace: "skpr-project"}, got)
+
+	got, err = WithDescription(DescriptionPrecedenceDescription, fromTags, fromDescription)
+	assert.NoError(t, err)
+	assert.Equal(t, fromDescription, got)
+
+	_, err = WithDescription("unknown", fromTags, fromDescription)
+	assert.Error(t, err)
+}
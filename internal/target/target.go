@@ -0,0 +1,113 @@
+package target
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	skpraws "github.com/skpr/lambda-eks-event-cloudwatch/pkg/aws"
+)
+
+// Target describes the Kubernetes object an event will be associated with.
+type Target struct {
+	Cluster    string `json:"cluster,omitempty"`
+	APIGroup   string `json:"apiGroup,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Selector   string `json:"selector,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Severity   string `json:"severity,omitempty"`
+	Owner      string `json:"owner,omitempty"`
+	OwnerMode  string `json:"ownerMode,omitempty"`
+	Sinks      string `json:"sinks,omitempty"`
+	Action     string `json:"action,omitempty"`
+}
+
+// FromTags builds a target from a set of resource tags.
+func FromTags(tags map[string]string) Target {
+	return Target{
+		Cluster:    tags[skpraws.TagKeyCluster],
+		APIGroup:   tags[skpraws.TagKeyAPIGroup],
+		APIVersion: tags[skpraws.TagKeyAPIVersion],
+		Kind:       tags[skpraws.TagKeyKind],
+		Namespace:  tags[skpraws.TagKeyNamespace],
+		Name:       tags[skpraws.TagKeyName],
+		Selector:   tags[skpraws.TagKeySelector],
+		Reason:     tags[skpraws.TagKeyReason],
+		Severity:   tags[skpraws.TagKeySeverity],
+		Owner:      tags[skpraws.TagKeyOwner],
+		OwnerMode:  tags[skpraws.TagKeyOwnerMode],
+		Sinks:      tags[skpraws.TagKeySinks],
+		Action:     tags[skpraws.TagKeyAction],
+	}
+}
+
+// Merge returns a copy of the target with any empty fields populated from the fallback.
+func (t Target) Merge(fallback Target) Target {
+	merged := t
+
+	for _, field := range []struct {
+		value    *string
+		fallback string
+	}{
+		{&merged.Cluster, fallback.Cluster},
+		{&merged.APIGroup, fallback.APIGroup},
+		{&merged.APIVersion, fallback.APIVersion},
+		{&merged.Kind, fallback.Kind},
+		{&merged.Namespace, fallback.Namespace},
+		{&merged.Name, fallback.Name},
+		{&merged.Selector, fallback.Selector},
+		{&merged.Reason, fallback.Reason},
+		{&merged.Severity, fallback.Severity},
+		{&merged.Owner, fallback.Owner},
+		{&merged.OwnerMode, fallback.OwnerMode},
+		{&merged.Sinks, fallback.Sinks},
+		{&merged.Action, fallback.Action},
+	} {
+		if *field.value == "" {
+			*field.value = field.fallback
+		}
+	}
+
+	return merged
+}
+
+// Validate that the target has all the fields required to emit an event.
+func (t Target) Validate() error {
+	if t.Cluster == "" {
+		return fmt.Errorf("cluster is required")
+	}
+
+	if t.APIVersion == "" {
+		return fmt.Errorf("api version is required")
+	}
+
+	if t.Kind == "" {
+		return fmt.Errorf("kind is required")
+	}
+
+	if t.Name == "" && t.Selector == "" {
+		return fmt.Errorf("name or selector is required")
+	}
+
+	if t.Name != "" && t.Selector != "" {
+		return fmt.Errorf("name and selector cannot both be set")
+	}
+
+	if t.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+
+	return nil
+}
+
+// GroupVersionKind of the target object.
+func (t Target) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   t.APIGroup,
+		Version: t.APIVersion,
+		Kind:    t.Kind,
+	}
+}
@@ -0,0 +1,129 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+)
+
+// Name of the tracer and service.
+const Name = "lambda-eks-event-cloudwatch"
+
+// EnvTraceID is the environment variable which Lambda uses to pass the X-Ray trace header.
+const EnvTraceID = "_X_AMZN_TRACE_ID"
+
+// DefaultExportTimeout is the timeout for each export to the collector when OTEL_EXPORTER_OTLP_TIMEOUT is not set.
+const DefaultExportTimeout = 5 * time.Second
+
+// Provider wraps the tracer provider so it can be flushed at the end of each invocation.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+}
+
+// NewExporter creates an OTLP HTTP exporter configured using the standard OTEL_EXPORTER_OTLP_* environment variables.
+// Exports time out after DefaultExportTimeout unless a timeout is configured, so a slow collector cannot stall the invocation.
+func NewExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	var options []otlptracehttp.Option
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_TIMEOUT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_TIMEOUT") == "" {
+		options = append(options, otlptracehttp.WithTimeout(DefaultExportTimeout))
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	return exporter, nil
+}
+
+// Setup registers a global tracer provider which batches spans and sends them to the exporter in the background, so
+// ending a span never blocks the request path. An in-memory exporter from go.opentelemetry.io/otel/sdk/trace/tracetest can
+// be used for tests.
+func Setup(exporter sdktrace.SpanExporter, version string) *Provider {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(Name),
+			semconv.ServiceVersion(version),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(xray.Propagator{}, propagation.TraceContext{}))
+
+	return &Provider{
+		provider: provider,
+	}
+}
+
+// Flush any buffered spans. This must be called before the invocation ends because Lambda freezes the environment.
+func (p *Provider) Flush(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	return p.provider.ForceFlush(ctx)
+}
+
+// Extract the Lambda X-Ray trace context so spans are joined to the invocation's trace.
+func Extract(ctx context.Context, header string) context.Context {
+	if header == "" {
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{
+		"X-Amzn-Trace-Id": header,
+	})
+}
+
+// Start a span using the global tracer provider.
+func Start(ctx context.Context, name string, attributes ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(Name).Start(ctx, name, attributes...)
+}
+
+// End a span, recording the error if there was one.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// InstrumentAWS adds tracing middleware to AWS SDK clients created from the config.
+func InstrumentAWS(cfg *aws.Config) {
+	otelaws.AppendMiddlewares(&cfg.APIOptions)
+}
+
+// HTTPClient returns a client which traces each request, and gives up on requests which take longer than the timeout.
+func HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+		Timeout:   timeout,
+	}
+}
+
+// InstrumentKubernetes wraps the client-go transport so each API server request is traced.
+func InstrumentKubernetes(config *rest.Config) {
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return otelhttp.NewTransport(rt)
+	})
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/client-go/rest"
+)
+
+func TestSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	provider := Setup(exporter, "test")
+
+	ctx := Extract(context.TODO(), "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+
+	ctx, parent := Start(ctx, "parent")
+
+	_, child := Start(ctx, "child")
+	End(child, fmt.Errorf("failed"))
+
+	End(parent, nil)
+
+	assert.NoError(t, provider.Flush(context.TODO()))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+
+	// The parent is joined to the Lambda X-Ray trace.
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", spans[1].SpanContext.TraceID().String())
+}
+
+func TestInstrumentKubernetes(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	provider := Setup(exporter, "test")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	InstrumentKubernetes(config)
+
+	client, err := rest.HTTPClientFor(config)
+	assert.NoError(t, err)
+
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.NoError(t, provider.Flush(context.TODO()))
+	assert.Len(t, exporter.GetSpans(), 1)
+}
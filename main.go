@@ -2,161 +2,1201 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	awscloudwatch "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/action"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/allow"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/batch"
 	"github.com/skpr/lambda-eks-event-cloudwatch/internal/cloudwatch"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/deadline"
 	skpreks "github.com/skpr/lambda-eks-event-cloudwatch/internal/eks"
-	"github.com/skpr/lambda-eks-event-cloudwatch/pkg/annotation"
-	skpraws "github.com/skpr/lambda-eks-event-cloudwatch/pkg/aws"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/eventbridge"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/flap"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/forwarder"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/metrics"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/permissions"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/report"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/resolver"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/rollout"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/rules"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/selftest"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/severity"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/sink"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/suppress"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/target"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/tracing"
 )
 
 var (
 	// GitVersion overridden at build time by:
 	//   -ldflags="-X main.GitVersion=${VERSION}"
 	GitVersion string
+
+	// provider used to flush spans at the end of each invocation when tracing is enabled.
+	provider *tracing.Provider
+
+	// connect creates the forwarder for a cluster. It is replaced in tests which don't have a cluster to connect to.
+	connect = newForwarder
+)
+
+const (
+	// EnvRulesFile is a local path or s3://bucket/key URI for a rules file used to route alarms.
+	EnvRulesFile = "RULES_FILE"
+	// EnvRulesPrecedence determines whether "tags" (default) or "rules" win when both provide a value.
+	EnvRulesPrecedence = "RULES_PRECEDENCE"
+	// EnvDescriptionPrecedence determines whether "tags" (default) or "description" win when both provide a value.
+	EnvDescriptionPrecedence = "DESCRIPTION_PRECEDENCE"
+	// EnvSelectorLimit is the maximum number of objects a selector can match before the event is rejected.
+	EnvSelectorLimit = "SELECTOR_LIMIT"
+	// EnvOwnerDepth is the maximum number of owner references which will be walked.
+	EnvOwnerDepth = "OWNER_DEPTH"
+	// EnvFallback determines what happens when the target object is missing: "none" (default), "namespace", "dangling" or "drop".
+	EnvFallback = "FALLBACK"
+	// EnvEventTypePolicy maps severity and alarm state to an event type eg. "info:ALARM=Warning,*:OK=Normal".
+	EnvEventTypePolicy = "EVENT_TYPE_POLICY"
+	// EnvSuppressionWindows is a JSON list of recurring windows where events are suppressed.
+	EnvSuppressionWindows = "SUPPRESSION_WINDOWS"
+	// EnvFlapThreshold is the number of transitions within the flap window before an alarm is considered flapping.
+	EnvFlapThreshold = "FLAP_THRESHOLD"
+	// EnvFlapWindow is the period which transitions are counted over eg. 10m.
+	EnvFlapWindow = "FLAP_WINDOW"
+	// EnvRateLimit is the maximum number of events which can be created in a namespace per rate window.
+	EnvRateLimit = "NAMESPACE_RATE_LIMIT"
+	// EnvRateWindow is the period which the namespace rate limit is applied over eg. 1m.
+	EnvRateWindow = "NAMESPACE_RATE_WINDOW"
+	// EnvStalenessLimit is the maximum delivery lag before an event is considered stale eg. 15m.
+	EnvStalenessLimit = "STALENESS_LIMIT"
+	// EnvStalenessAction determines what happens to stale events: "mark" (default) or "drop".
+	EnvStalenessAction = "STALENESS_ACTION"
+	// EnvAllowlist is a JSON object of policies keyed by cluster name which restrict the namespaces and kinds alarms can write to.
+	EnvAllowlist = "ALLOWLIST"
+	// EnvClusterNamespace is the namespace which events for cluster-scoped objects are created in (default: default).
+	EnvClusterNamespace = "CLUSTER_SCOPED_NAMESPACE"
+	// EnvResolveAction determines what happens to Warning events when the alarm returns to OK: "event" (default), "delete" or "annotate".
+	EnvResolveAction = "RESOLVE_ACTION"
+	// EnvRolloutLookback is the window which alarms are correlated with the last rollout of the target over eg. 1h. Unset disables correlation.
+	EnvRolloutLookback = "ROLLOUT_LOOKBACK"
+	// EnvWebhookURL is the URL which the webhook sink posts alarms to.
+	EnvWebhookURL = "WEBHOOK_URL"
+	// EnvWebhookSecret is used to sign webhook payloads with HMAC SHA256.
+	EnvWebhookSecret = "WEBHOOK_SECRET"
+	// EnvSlackWebhookURL is the Slack incoming webhook which the slack sink posts alarms to.
+	EnvSlackWebhookURL = "SLACK_WEBHOOK_URL"
+	// EnvActionRules is a JSON list of rules which permit remediation actions on kinds in namespaces.
+	EnvActionRules = "ACTION_RULES"
+	// EnvActionCooldown is the minimum time between remediation actions on the same object eg. 15m.
+	EnvActionCooldown = "ACTION_COOLDOWN"
+	// EnvActionDryRun creates audit events for remediation actions without executing them.
+	EnvActionDryRun = "ACTION_DRY_RUN"
+	// EnvBatchConcurrency is the number of batched records prepared, and clusters processed, at the same time.
+	EnvBatchConcurrency = "BATCH_CONCURRENCY"
+	// EnvClusterConcurrency is the number of alarms processed at the same time for each cluster.
+	EnvClusterConcurrency = "CLUSTER_CONCURRENCY"
+	// EnvTracingEnabled enables OpenTelemetry tracing, exported using the standard OTEL_EXPORTER_OTLP_* variables.
+	EnvTracingEnabled = "TRACING_ENABLED"
+	// EnvDeadlineMargin is how long before the Lambda deadline the invocation gives up eg. 2s.
+	EnvDeadlineMargin = "DEADLINE_MARGIN"
+	// EnvStageTimeout is the maximum time for each AWS stage eg. looking up tags or describing the cluster.
+	EnvStageTimeout = "STAGE_TIMEOUT"
+	// EnvKubeTimeout is the timeout for each Kubernetes API request.
+	EnvKubeTimeout = "KUBE_TIMEOUT"
+	// EnvKubeQPS is the maximum queries per second to the Kubernetes API.
+	EnvKubeQPS = "KUBE_QPS"
+	// EnvKubeBurst is the maximum burst of queries to the Kubernetes API.
+	EnvKubeBurst = "KUBE_BURST"
+)
+
+const (
+	// DefaultKubeTimeout is the default timeout for each Kubernetes API request.
+	DefaultKubeTimeout = 10 * time.Second
+	// DefaultKubeQPS is the default maximum queries per second to the Kubernetes API.
+	DefaultKubeQPS = 20
+	// DefaultKubeBurst is the default maximum burst of queries to the Kubernetes API.
+	DefaultKubeBurst = 40
 )
 
 func main() {
+	if os.Getenv(EnvTracingEnabled) == "true" {
+		exporter, err := tracing.NewExporter(context.Background())
+		if err != nil {
+			logging.New(os.Stdout).Error("Failed to set up tracing", "error", err.Error())
+			os.Exit(1)
+		}
+
+		provider = tracing.Setup(exporter, GitVersion)
+	}
+
+	var (
+		selfTest = flag.Bool("selftest", false, "Check the IAM and RBAC permissions required to forward alarms and exit")
+		req      selftest.Request
+	)
+
+	flag.StringVar(&req.Cluster, "cluster", "", "Cluster to check permissions against")
+	flag.StringVar(&req.Namespace, "namespace", "", "Namespace to check permissions in")
+	flag.StringVar(&req.AlarmARN, "alarm-arn", "", "Alarm to check tags can be listed for")
+	flag.StringVar(&req.APIGroup, "api-group", "", "API group of the target resource eg. workflow.skpr.io")
+	flag.StringVar(&req.Resource, "resource", "", "Plural name of the target resource eg. environments")
+
+	var (
+		output     = flag.String("permissions", "", "Print the permissions required by the enabled features and exit: iam, rbac or mapping")
+		name       = flag.String("name", tracing.Name, "Name of the ClusterRole, bindings and Kubernetes group")
+		namespaces = flag.String("namespaces", "", "Comma separated namespaces which events are created in (default: all)")
+		targets    = flag.String("targets", "", "Comma separated resources which events are associated with eg. workflow.skpr.io/environments,pods")
+		owners     = flag.String("owners", "", "Comma separated resources which owner references are walked to eg. apps/deployments")
+		selector   = flag.Bool("selector", false, "Targets are matched using label selectors")
+		logs       = flag.Bool("logs", false, "CloudWatch Logs subscription payloads are routed using log group tags")
+		services   = flag.Bool("services", false, "RDS, ElastiCache and AWS Health events are routed using resource tags")
+		scoped     = flag.String("cluster-scoped", "", "Comma separated custom resources which are cluster-scoped eg. example.com/clusters")
+		mapping    = flag.String("mapping", permissions.MappingAccessEntry, "How the IAM role is mapped to the Kubernetes group: access-entry or aws-auth")
+		roleARN    = flag.String("role-arn", "", "IAM role used by the function")
+	)
+
+	flag.Parse()
+
+	if *selfTest {
+		os.Exit(runSelfTest(req))
+	}
+
+	if *output != "" {
+		features := permissions.Features{
+			Name:             *name,
+			Cluster:          req.Cluster,
+			ClusterNamespace: os.Getenv(EnvClusterNamespace),
+			RulesFile:        os.Getenv(EnvRulesFile),
+			Selector:         *selector,
+			Logs:             *logs,
+			Services:         *services,
+		}
+
+		os.Exit(runPermissions(features, *output, *namespaces, *targets, *owners, *scoped, *mapping, *roleARN))
+	}
+
+	// Misconfigured policies fail at startup rather than never matching.
+	if _, err := severity.ParsePolicy(os.Getenv(EnvEventTypePolicy)); err != nil {
+		logging.New(os.Stdout).Error("Failed to parse event type policy", "error", err.Error())
+		os.Exit(1)
+	}
+
 	lambda.Start(HandleLambdaEvent)
 }
 
-// HandleLambdaEvent will respond to a CloudWatch Alarm, check for rate limited IP addresses and send a message to Slack.
-func HandleLambdaEvent(ctx context.Context, event *cloudwatch.Event) error {
-	log.Printf("Running Lambda (%s)\n", GitVersion)
+// runSelfTest prints the self-test report and returns the exit code.
+func runSelfTest(req selftest.Request) int {
+	report, err := handleSelfTest(context.Background(), req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Println(string(data))
+
+	if !report.Passed {
+		return 1
+	}
+
+	return 0
+}
+
+// runPermissions prints the permissions required by the features and returns the exit code.
+// Feature settings are read from the same environment variables as the function.
+func runPermissions(features permissions.Features, output, namespaces, targets, owners, scoped, mapping, roleARN string) int {
+	data, err := printPermissions(features, output, namespaces, targets, owners, scoped, mapping, roleARN)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Print(string(data))
+
+	return 0
+}
+
+// printPermissions renders the requested permissions output.
+func printPermissions(features permissions.Features, output, namespaces, targets, owners, scoped, mapping, roleARN string) ([]byte, error) {
+	var err error
+
+	for _, namespace := range strings.Split(namespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			features.Namespaces = append(features.Namespaces, namespace)
+		}
+	}
+
+	features.Targets, err = permissions.ParseResources(targets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse targets: %w", err)
+	}
+
+	features.Owners, err = permissions.ParseResources(owners)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse owners: %w", err)
+	}
+
+	features.ClusterScoped, err = permissions.ParseResources(scoped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cluster scoped resources: %w", err)
+	}
+
+	flapThreshold, err := getEnvInt(EnvFlapThreshold, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimit, err := getEnvInt(EnvRateLimit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	features.Flapping = flapThreshold > 0
+	features.RateLimit = rateLimit > 0
+
+	// Dry runs still record the cooldown annotation, so they also require patch.
+	features.Actions = os.Getenv(EnvActionRules) != ""
+
+	rolloutLookback, err := getEnvDuration(EnvRolloutLookback, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	features.Rollouts = rolloutLookback > 0
+
+	switch os.Getenv(EnvResolveAction) {
+	case forwarder.ResolveDelete:
+		features.ResolveVerb = "delete"
+	case forwarder.ResolveAnnotate:
+		features.ResolveVerb = "patch"
+	}
+
+	switch output {
+	case "iam":
+		policy, err := permissions.IAMPolicy(features)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.MarshalIndent(policy, "", "    ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal policy: %w", err)
+		}
+
+		return append(data, '\n'), nil
+	case "rbac":
+		objects, err := permissions.RBAC(features)
+		if err != nil {
+			return nil, err
+		}
+
+		return permissions.Marshal(objects...)
+	case "mapping":
+		if roleARN == "" {
+			return nil, fmt.Errorf("role ARN is required")
+		}
+
+		object, err := permissions.Mapping(features, mapping, roleARN)
+		if err != nil {
+			return nil, err
+		}
+
+		if mapping != permissions.MappingAWSAuth {
+			data, err := json.MarshalIndent(object, "", "    ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal access entry: %w", err)
+			}
+
+			return append(data, '\n'), nil
+		}
+
+		return permissions.Marshal(object)
+	}
+
+	return nil, fmt.Errorf("unknown permissions output: %s", output)
+}
+
+// Payload received by the Lambda. This is either a CloudWatch Alarm event, a CloudWatch Logs subscription payload, an
+// EventBridge event from a supported AWS service, a batch of SQS or SNS records carrying these, or a self-test request.
+type Payload struct {
+	cloudwatch.Event
+	*cloudwatch.LogsEvent
+	*eventbridge.ServiceEvent
+	Records  []batch.Record    `json:"Records,omitempty"`
+	SelfTest *selftest.Request `json:"selftest,omitempty"`
+}
+
+// record is a single payload to be processed, which is the invocation payload itself unless records are batched.
+type record struct {
+	id      string
+	payload *Payload
+	err     error
+}
+
+// records returns the payloads carried by the invocation.
+func records(payload *Payload) []record {
+	if len(payload.Records) == 0 {
+		return []record{{payload: payload}}
+	}
+
+	list := make([]record, len(payload.Records))
+
+	for i, r := range payload.Records {
+		list[i].id = r.ID()
+		list[i].payload = &Payload{}
+
+		// Alarms with an SNS action publish a notification rather than the Lambda alarm action payload.
+		event, err := cloudwatch.ParseNotification(r.Payload())
+		if err == nil && event != nil {
+			list[i].payload.Event = *event
+			continue
+		}
+
+		if err := json.Unmarshal(r.Payload(), list[i].payload); err != nil {
+			list[i].err = fmt.Errorf("failed to unmarshal record: %w", err)
+		}
+	}
+
+	return list
+}
+
+// Response returned by the Lambda describing what was done for each target. When invoked from SQS, failed records are
+// returned as batch item failures rather than failing the invocation.
+type Response struct {
+	SelfTest          *selftest.Report    `json:"selftest,omitempty"`
+	Targets           []*report.Target    `json:"targets,omitempty"`
+	BatchItemFailures []batch.ItemFailure `json:"batchItemFailures,omitempty"`
+}
+
+// HandleLambdaEvent will respond to a CloudWatch Alarm by sending it to the selected sinks, which default to creating
+// events on the target Kubernetes objects.
+func HandleLambdaEvent(ctx context.Context, payload *Payload) (*Response, error) {
+	var (
+		event    = &payload.Event
+		logger   = logging.New(os.Stdout).With("version", GitVersion)
+		recorder = metrics.New(os.Stdout)
+	)
+
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		logger = logger.With("request_id", lc.AwsRequestID)
+	}
+
+	ctx = logging.WithContext(ctx, logger)
+	ctx = metrics.WithContext(ctx, recorder)
+	ctx = tracing.Extract(ctx, os.Getenv(tracing.EnvTraceID))
+
+	if payload.SelfTest != nil {
+		logger.Info("Running self-test", "cluster", payload.SelfTest.Cluster)
+
+		report, err := handleSelfTest(ctx, *payload.SelfTest)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Info("Self-test complete", "passed", report.Passed)
+
+		return &Response{SelfTest: report}, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "HandleLambdaEvent", trace.WithAttributes(attribute.String("alarm.arn", event.AlarmARN)))
+
+	response := &Response{}
+
+	margin, err := getEnvDuration(EnvDeadlineMargin, deadline.DefaultMargin)
+	if err == nil {
+		logger.Info("Calculated remaining time", "remaining", deadline.Remaining(ctx).String(), "margin", margin.String())
+
+		// Give up before Lambda kills the invocation so the retry is clean.
+		handleCtx, cancel := deadline.WithMargin(ctx, margin)
+
+		var (
+			list = records(payload)
+			errs []error
+		)
+
+		response.Targets, errs, err = process(handleCtx, list)
+
+		if err == nil && batch.SQS(payload.Records) {
+			for i, recordErr := range errs {
+				if recordErr == nil {
+					continue
+				}
+
+				recordErr = deadline.Wrap(handleCtx, recordErr)
+
+				recorder.Failed(recordErr)
+				logger.Error("Record failed", "outcome", "failed", "message_id", list[i].id, "error_class", metrics.Classify(recordErr), "error", recordErr.Error())
+
+				response.BatchItemFailures = append(response.BatchItemFailures, batch.ItemFailure{ItemIdentifier: list[i].id})
+			}
+		} else if err == nil {
+			err = errors.Join(errs...)
+		}
+
+		err = deadline.Wrap(handleCtx, err)
+		cancel()
+	}
+
+	tracing.End(span, err)
+
+	if err := provider.Flush(ctx); err != nil {
+		logger.Error("Failed to flush spans", "error", err.Error())
+	}
+
+	if err != nil {
+		recorder.Failed(err)
+		logger.Error("Function failed", "outcome", "failed", "error_class", metrics.Classify(err), "error", err.Error())
+	} else {
+		logger.Info("Function complete")
+	}
+
+	if err := recorder.Flush(); err != nil {
+		logger.Error("Failed to flush metrics", "error", err.Error())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// process prepares the alarms for each record concurrently, then sends them to the selected sinks grouped by cluster so
+// each cluster is connected to once. A report is returned for each target, and errors are returned for each record.
+func process(ctx context.Context, list []record) ([]*report.Target, []error, error) {
+	concurrency, err := getEnvInt(EnvBatchConcurrency, batch.DefaultConcurrency)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groupConcurrency, err := getEnvInt(EnvClusterConcurrency, batch.DefaultGroupConcurrency)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	cfg, err := awsconfig.LoadDefaultConfig(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to load SDK config, %v", err)
+		return nil, nil, fmt.Errorf("unable to load SDK config, %v", err)
+	}
+
+	tracing.InstrumentAWS(&cfg)
+
+	file, err := loadRules(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	log.Printf("Validating event")
+	if len(list) > 1 {
+		logging.FromContext(ctx).Info("Processing batch", "records", len(list))
+	}
+
+	var (
+		errs     = make([]error, len(list))
+		alarms   = make([][]forwarder.Alarm, len(list))
+		contexts = make([]context.Context, len(list))
+	)
+
+	batch.Each(len(list), concurrency, func(i int) {
+		contexts[i] = ctx
+		if list[i].id != "" {
+			contexts[i] = logging.With(ctx, "message_id", list[i].id)
+		}
+
+		if list[i].err != nil {
+			errs[i] = list[i].err
+			return
+		}
+
+		alarms[i], errs[i] = prepare(contexts[i], cfg, file, list[i].payload)
+	})
+
+	connections := sink.NewConnections(func(ctx context.Context, cluster string) (*forwarder.Forwarder, error) {
+		return connect(ctx, cfg, cluster)
+	})
+
+	var (
+		tasks   []batch.Task
+		targets []*report.Target
+		// Clusters are processed concurrently, so each has its own recorder for the Cluster dimension.
+		recorders = make(map[string]*metrics.Recorder)
+	)
+
+	for i := range list {
+		// Records which failed before being sent to a target are reported on their own.
+		if errs[i] != nil {
+			t := &report.Target{Record: list[i].id}
+			t.Fail(errs[i])
+			targets = append(targets, t)
+		}
+
+		for _, alarm := range alarms[i] {
+			t := &report.Target{
+				Record:  list[i].id,
+				Alarm:   alarm.Name,
+				Cluster: alarm.Target.Cluster,
+				Target:  fmt.Sprintf("%s/%s/%s%s", alarm.Target.Kind, alarm.Target.Namespace, alarm.Target.Name, alarm.Target.Selector),
+				Sinks:   sink.Parse(alarm.Target.Sinks),
+			}
+
+			targets = append(targets, t)
+
+			recorder, ok := recorders[alarm.Target.Cluster]
+			if !ok {
+				recorder = metrics.New(os.Stdout)
+
+				if alarm.Target.Cluster != "" {
+					recorder.SetDimension(metrics.DimensionCluster, alarm.Target.Cluster)
+				}
+
+				recorders[alarm.Target.Cluster] = recorder
+			}
+
+			tasks = append(tasks, batch.Task{
+				Record: i,
+				Group:  alarm.Target.Cluster,
+				Key:    alarm.ARN,
+				Run: func(context.Context) error {
+					err := dispatch(report.WithContext(metrics.WithContext(contexts[i], recorder), t), connections, alarm)
+					t.Fail(err)
+					return err
+				},
+			})
+		}
+	}
+
+	for j, err := range batch.Run(ctx, tasks, batch.Options{Concurrency: concurrency, GroupConcurrency: groupConcurrency}) {
+		if err != nil {
+			errs[tasks[j].Record] = errors.Join(errs[tasks[j].Record], err)
+		}
+	}
+
+	for _, recorder := range recorders {
+		if err := recorder.Flush(); err != nil {
+			logging.FromContext(ctx).Error("Failed to flush metrics", "error", err.Error())
+		}
+	}
+
+	return targets, errs, nil
+}
+
+// prepare converts the payload into alarms with resolved targets.
+func prepare(ctx context.Context, cfg aws.Config, file *rules.File, payload *Payload) ([]forwarder.Alarm, error) {
+	switch {
+	case payload.LogsEvent != nil:
+		return prepareLogs(ctx, cfg, file, payload.LogsEvent)
+	case payload.ServiceEvent != nil && payload.ServiceEvent.Supported():
+		return prepareService(ctx, cfg, file, payload.ServiceEvent)
+	}
+
+	return prepareAlarm(ctx, cfg, file, &payload.Event)
+}
+
+// prepareAlarm resolves the target for the alarm.
+func prepareAlarm(ctx context.Context, cfg aws.Config, file *rules.File, event *cloudwatch.Event) ([]forwarder.Alarm, error) {
+	ctx = logging.With(ctx, "alarm_arn", event.AlarmARN)
+
+	var (
+		logger   = logging.FromContext(ctx)
+		recorder = metrics.FromContext(ctx)
+	)
+
+	logger.Info("Running Lambda")
+
+	stageTimeout, err := getEnvDuration(EnvStageTimeout, deadline.DefaultStageTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Validating event")
 
 	if event.AlarmARN == "" {
-		return fmt.Errorf("alarm ARN is required")
+		return nil, fmt.Errorf("alarm ARN is required")
 	}
 
 	if event.AlarmData.Configuration.Description == "" {
-		return fmt.Errorf("alarm configuration description is required")
+		return nil, fmt.Errorf("alarm configuration description is required")
 	}
 
-	log.Printf("Looking up alarm tags")
+	logger.Info("Looking up alarm tags")
+
+	stop := recorder.Stage(metrics.StageTags)
+	spanCtx, span := tracing.Start(ctx, metrics.StageTags)
+	stageCtx, cancel := deadline.Stage(spanCtx, stageTimeout)
 
-	alarm, err := awscloudwatch.NewFromConfig(cfg).ListTagsForResource(ctx, &awscloudwatch.ListTagsForResourceInput{
+	alarm, err := awscloudwatch.NewFromConfig(cfg).ListTagsForResource(stageCtx, &awscloudwatch.ListTagsForResourceInput{
 		ResourceARN: aws.String(event.AlarmARN),
 	})
+	cancel()
+	tracing.End(span, err)
 	if err != nil {
-		return fmt.Errorf("failed to list tags for resource: %w", err)
+		return nil, fmt.Errorf("failed to list tags for resource: %w", err)
 	}
 
-	cluster, err := cloudwatch.GetValueFromTag(alarm.Tags, skpraws.TagKeyCluster)
+	stop()
+
+	fromDescription, message, err := target.FromDescription(event.AlarmData.Configuration.Description)
 	if err != nil {
-		return fmt.Errorf("failed to get cluster from tags: %w", err)
+		return nil, fmt.Errorf("failed to parse alarm description: %w", err)
 	}
 
-	apiGroup, err := cloudwatch.GetValueFromTag(alarm.Tags, skpraws.TagKeyAPIGroup)
+	if message == "" {
+		message = event.AlarmData.AlarmName
+	}
+
+	fromTags, err := target.WithDescription(os.Getenv(EnvDescriptionPrecedence), target.FromTags(cloudwatch.TagsToMap(alarm.Tags)), fromDescription)
 	if err != nil {
-		return fmt.Errorf("failed to get api version from tags: %w", err)
+		return nil, err
 	}
 
-	apiVersion, err := cloudwatch.GetValueFromTag(alarm.Tags, skpraws.TagKeyAPIVersion)
+	resolved, err := route(ctx, file, fromTags, rules.AlarmFromEvent(event))
 	if err != nil {
-		return fmt.Errorf("failed to get api version from tags: %w", err)
+		return nil, err
 	}
 
-	kind, err := cloudwatch.GetValueFromTag(alarm.Tags, skpraws.TagKeyKind)
+	sev, err := severity.Parse(resolved.Severity)
 	if err != nil {
-		return fmt.Errorf("failed to get kind from tags: %w", err)
+		return nil, fmt.Errorf("failed to parse severity: %w", err)
 	}
 
-	namespace, err := cloudwatch.GetValueFromTag(alarm.Tags, skpraws.TagKeyNamespace)
+	timestamp, err := cloudwatch.ParseTimestamp(event.AlarmData.State.Timestamp)
 	if err != nil {
-		return fmt.Errorf("failed to get namespace from tags: %w", err)
+		return nil, fmt.Errorf("failed to parse state timestamp: %w", err)
 	}
 
-	name, err := cloudwatch.GetValueFromTag(alarm.Tags, skpraws.TagKeyName)
+	previousTimestamp, err := cloudwatch.ParseTimestamp(event.AlarmData.PreviousState.Timestamp)
 	if err != nil {
-		return fmt.Errorf("failed to get name from tags: %w", err)
+		return nil, fmt.Errorf("failed to parse previous state timestamp: %w", err)
 	}
 
-	reason, err := cloudwatch.GetValueFromTag(alarm.Tags, skpraws.TagKeyReason)
+	// The datapoint summary is added to the message, so the alarm is still forwarded without it if it can't be parsed.
+	reasonData, err := cloudwatch.ParseReasonData(event.AlarmData.State.ReasonData)
 	if err != nil {
-		return fmt.Errorf("failed to get reason from tags: %w", err)
+		logger.Warn("Ignoring invalid reason data", "error", err.Error())
+		reasonData = nil
 	}
 
-	log.Printf("Connecting to EKS cluster")
+	var annotations map[string]string
 
-	config, err := skpreks.BuildKubeconfig(ctx, eks.NewFromConfig(cfg), cluster)
+	if reasonData != nil {
+		annotations, err = reasonData.Annotations()
+		if err != nil {
+			logger.Warn("Ignoring invalid reason data", "error", err.Error())
+		} else {
+			message = fmt.Sprintf("%s (%s)", message, reasonData.Summary(event.AlarmData.Configuration.Metric(), event.AlarmData.State.Reason))
+		}
+	}
+
+	return []forwarder.Alarm{{
+		Name:              event.AlarmData.AlarmName,
+		ARN:               event.AlarmARN,
+		State:             event.AlarmData.State.Value,
+		Description:       message,
+		Severity:          sev,
+		Target:            resolved,
+		Timestamp:         timestamp,
+		PreviousTimestamp: previousTimestamp,
+		Annotations:       annotations,
+	}}, nil
+}
+
+// prepareLogs aggregates a CloudWatch Logs subscription payload into a single alarm.
+func prepareLogs(ctx context.Context, cfg aws.Config, file *rules.File, event *cloudwatch.LogsEvent) ([]forwarder.Alarm, error) {
+	var (
+		logger   = logging.FromContext(ctx)
+		recorder = metrics.FromContext(ctx)
+	)
+
+	data, err := event.Decode()
 	if err != nil {
-		return fmt.Errorf("failed to get kubernetes config: %w", err)
+		return nil, err
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	if data.MessageType != cloudwatch.MessageTypeData {
+		logger.Info("Ignoring logs message", "message_type", data.MessageType)
+		return nil, nil
+	}
+
+	ctx = logging.With(ctx, "log_group", data.LogGroup, "log_events", len(data.LogEvents))
+	logger = logging.FromContext(ctx)
+
+	stageTimeout, err := getEnvDuration(EnvStageTimeout, deadline.DefaultStageTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to get kubernetes clientset: %w", err)
+		return nil, err
 	}
 
-	client, err := dynamic.NewForConfig(config)
+	arn := data.LogGroupARN(cfg.Region)
+
+	logger.Info("Looking up log group tags")
+
+	stop := recorder.Stage(metrics.StageTags)
+	spanCtx, span := tracing.Start(ctx, metrics.StageTags)
+	stageCtx, cancel := deadline.Stage(spanCtx, stageTimeout)
+
+	group, err := cloudwatchlogs.NewFromConfig(cfg).ListTagsForResource(stageCtx, &cloudwatchlogs.ListTagsForResourceInput{
+		ResourceArn: aws.String(arn),
+	})
+	cancel()
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for log group: %w", err)
+	}
+
+	stop()
+
+	resolved, err := route(ctx, file, target.FromTags(group.Tags), rules.Alarm{
+		Name:     data.Filter(),
+		ARN:      arn,
+		LogGroup: data.LogGroup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sev, err := severity.Parse(resolved.Severity)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to parse severity: %w", err)
 	}
 
-	log.Printf("Looking up resource version and UID")
+	_, last := data.Timestamps()
 
-	res := schema.GroupVersionResource{Group: "workflow.skpr.io", Version: "v1beta1", Resource: "environments"}
+	return []forwarder.Alarm{{
+		Name:        data.Filter(),
+		ARN:         arn,
+		State:       severity.StateAlarm,
+		Description: data.Summary(cloudwatch.MessageLimit),
+		Severity:    sev,
+		Target:      resolved,
+		Timestamp:   last,
+	}}, nil
+}
 
-	unstructured, err := client.Resource(res).Namespace("skpr-project-drupal").Get(context.TODO(), "prod", metav1.GetOptions{})
+// prepareService converts an EventBridge event from an AWS service into an alarm for each of the tagged resources it
+// affects.
+func prepareService(ctx context.Context, cfg aws.Config, file *rules.File, event *eventbridge.ServiceEvent) ([]forwarder.Alarm, error) {
+	var (
+		logger   = logging.FromContext(ctx)
+		recorder = metrics.FromContext(ctx)
+	)
+
+	notice, err := eventbridge.Parse(event)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	log.Printf("Marshalling to Kubernetes event")
+	ctx = logging.With(ctx, "source", event.Source, "detail_type", event.DetailType, "event_id", event.ID)
+	logger = logging.FromContext(ctx)
 
-	object := &corev1.Event{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace:    namespace,
-			GenerateName: "aws-cloudwatch-alarm-",
-			Annotations: map[string]string{
-				annotation.KeyCloudWatchAlarmName: event.AlarmData.AlarmName,
-			},
-		},
-		InvolvedObject: corev1.ObjectReference{
-			APIVersion:      fmt.Sprintf("%s/%s", apiGroup, apiVersion),
-			Kind:            kind,
-			Namespace:       namespace,
-			Name:            name,
-			UID:             unstructured.GetUID(),
-			ResourceVersion: unstructured.GetResourceVersion(),
+	if len(notice.Resources) == 0 {
+		logger.Info("Ignoring service event without resources")
+		return nil, nil
+	}
+
+	stageTimeout, err := getEnvDuration(EnvStageTimeout, deadline.DefaultStageTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Looking up resource tags", "resources", len(notice.Resources))
+
+	stop := recorder.Stage(metrics.StageTags)
+	spanCtx, span := tracing.Start(ctx, metrics.StageTags)
+	stageCtx, cancel := deadline.Stage(spanCtx, stageTimeout)
+
+	tags, err := eventbridge.Tags(stageCtx, resourcegroupstaggingapi.NewFromConfig(cfg), notice.Resources)
+	cancel()
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for resources: %w", err)
+	}
+
+	stop()
+
+	var alarms []forwarder.Alarm
+
+	for _, arn := range notice.Resources {
+		resolved, err := route(ctx, file, target.FromTags(tags[arn]), rules.Alarm{
+			Name: notice.Name,
+			ARN:  arn,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if resolved == (target.Target{}) {
+			logger.Info("Ignoring resource without a target", "resource", arn)
+			continue
+		}
+
+		// The reason describes what happened to the resource, so it is drawn from the service event.
+		resolved = target.Target{Reason: notice.Reason}.Merge(resolved)
+
+		sev, err := severity.Parse(resolved.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse severity: %w", err)
+		}
+
+		alarms = append(alarms, forwarder.Alarm{
+			Name:        notice.Name,
+			ARN:         arn,
+			State:       notice.State,
+			Description: notice.Message,
+			Severity:    sev,
+			Target:      resolved,
+			Timestamp:   notice.Timestamp,
+		})
+	}
+
+	return alarms, nil
+}
+
+// loadRules loads the rules file once per invocation so every record in a batch is routed by the same rules. Nil is
+// returned when no rules file is configured.
+func loadRules(ctx context.Context, cfg aws.Config) (*rules.File, error) {
+	source := os.Getenv(EnvRulesFile)
+	if source == "" {
+		return nil, nil
+	}
+
+	logging.FromContext(ctx).Info("Loading rules", "source", source)
+
+	stageTimeout, err := getEnvDuration(EnvStageTimeout, deadline.DefaultStageTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	stageCtx, cancel := deadline.Stage(ctx, stageTimeout)
+	defer cancel()
+
+	file, err := rules.Load(stageCtx, s3.NewFromConfig(cfg), source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	return file, nil
+}
+
+// route resolves the target from tags and the rules file, which is nil when not configured.
+func route(ctx context.Context, file *rules.File, fromTags target.Target, alarm rules.Alarm) (target.Target, error) {
+	var fromRule target.Target
+
+	if file != nil {
+		logging.FromContext(ctx).Info("Evaluating rules")
+
+		if rule, ok := file.Evaluate(alarm); ok {
+			fromRule = rule
+		}
+	}
+
+	resolved, err := rules.Resolve(rules.Precedence(os.Getenv(EnvRulesPrecedence)), fromTags, fromRule)
+	if err != nil {
+		return target.Target{}, fmt.Errorf("failed to resolve target: %w", err)
+	}
+
+	return resolved, nil
+}
+
+// dispatch sends the alarm to the sinks selected by its target.
+func dispatch(ctx context.Context, connections *sink.Connections, alarm forwarder.Alarm) error {
+	resolved := alarm.Target
+
+	ctx = logging.With(ctx, "cluster", resolved.Cluster, "target", fmt.Sprintf("%s/%s/%s%s", resolved.Kind, resolved.Namespace, resolved.Name, resolved.Selector))
+
+	var (
+		sinks = make(map[string]sink.Sink)
+		errs  []error
+	)
+
+	stageTimeout, err := getEnvDuration(EnvStageTimeout, deadline.DefaultStageTimeout)
+	if err != nil {
+		return err
+	}
+
+	// Sinks are created independently so one which is misconfigured does not stop the others.
+	for _, name := range sink.Parse(resolved.Sinks) {
+		s, err := newSink(name, resolved, connections, stageTimeout)
+		if err != nil {
+			err = fmt.Errorf("failed to create sink %s: %w", name, err)
+			sink.Failed(ctx, name, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		sinks[name] = s
+	}
+
+	if len(sinks) == 0 {
+		return errors.Join(errs...)
+	}
+
+	logging.FromContext(ctx).Info("Sending alarm to sinks", "sinks", resolved.Sinks)
+
+	return sink.Fanout(ctx, alarm, sinks)
+}
+
+// newSink creates the named sink using the configuration from the environment. Sinks which post to an endpoint are
+// limited to the stage timeout.
+func newSink(name string, resolved target.Target, connections *sink.Connections, stageTimeout time.Duration) (sink.Sink, error) {
+	switch name {
+	case sink.NameKubernetes:
+		if err := resolved.Validate(); err != nil {
+			return nil, fmt.Errorf("failed to validate target from tags and rules: %w", err)
+		}
+
+		return sink.NewKubernetes(connections.Connect(resolved.Cluster)), nil
+	case sink.NameWebhook:
+		webhook, err := sink.NewWebhook(tracing.HTTPClient(stageTimeout), os.Getenv(EnvWebhookURL), os.Getenv(EnvWebhookSecret))
+		if err != nil {
+			return nil, err
+		}
+
+		return sink.WithTimeout(webhook, stageTimeout), nil
+	case sink.NameSlack:
+		slack, err := sink.NewSlack(tracing.HTTPClient(stageTimeout), os.Getenv(EnvSlackWebhookURL))
+		if err != nil {
+			return nil, err
+		}
+
+		return sink.WithTimeout(slack, stageTimeout), nil
+	case sink.NameStdout:
+		return sink.NewStdout(os.Stdout), nil
+	}
+
+	return nil, fmt.Errorf("unknown sink: %s", name)
+}
+
+// newForwarder connects to the cluster and creates a forwarder using the configuration from the environment.
+func newForwarder(ctx context.Context, cfg aws.Config, cluster string) (*forwarder.Forwarder, error) {
+	policy, err := severity.ParsePolicy(os.Getenv(EnvEventTypePolicy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse event type policy: %w", err)
+	}
+
+	checker, err := suppress.New(os.Getenv(EnvSuppressionWindows))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load suppression windows: %w", err)
+	}
+
+	allowlist, err := allow.New(os.Getenv(EnvAllowlist))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load allowlist: %w", err)
+	}
+
+	limit, err := getEnvInt(EnvSelectorLimit, resolver.DefaultLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerDepth, err := getEnvInt(EnvOwnerDepth, resolver.DefaultOwnerDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	flapThreshold, err := getEnvInt(EnvFlapThreshold, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	flapWindow, err := getEnvDuration(EnvFlapWindow, flap.DefaultWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimit, err := getEnvInt(EnvRateLimit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rateWindow, err := getEnvDuration(EnvRateWindow, forwarder.DefaultRateWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	stalenessLimit, err := getEnvDuration(EnvStalenessLimit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	actionRules, err := action.ParseRules(os.Getenv(EnvActionRules))
+	if err != nil {
+		return nil, err
+	}
+
+	actionCooldown, err := getEnvDuration(EnvActionCooldown, action.DefaultCooldown)
+	if err != nil {
+		return nil, err
+	}
+
+	rolloutLookback, err := getEnvDuration(EnvRolloutLookback, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Connecting to EKS cluster")
+
+	config, err := kubeconfig(ctx, cfg, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes clientset: %w", err)
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes dynamic client: %w", err)
+	}
+
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+
+	r := resolver.New(client, mapper, resolver.Options{
+		Limit:      limit,
+		OwnerDepth: ownerDepth,
+	})
+
+	var rollouts *rollout.Finder
+
+	if rolloutLookback > 0 {
+		rollouts = rollout.New(client, rolloutLookback)
+	}
+
+	return forwarder.New(clientset, r, forwarder.Options{
+		Fallback: os.Getenv(EnvFallback),
+		Policy:   policy,
+		Checker:  checker,
+		Detector: flap.Detector{
+			Threshold: flapThreshold,
+			Window:    flapWindow,
 		},
-		Type:           corev1.EventTypeWarning,
-		Reason:         reason,
-		Message:        event.AlarmData.Configuration.Description,
-		FirstTimestamp: metav1.Now(),
-		LastTimestamp:  metav1.Now(),
-		Source: corev1.EventSource{
-			Component: "aws-cloudwatch-alarm",
+		RateLimit:        rateLimit,
+		RateWindow:       rateWindow,
+		StalenessLimit:   stalenessLimit,
+		StalenessAction:  os.Getenv(EnvStalenessAction),
+		Allow:            allowlist,
+		ClusterNamespace: os.Getenv(EnvClusterNamespace),
+		ResolveAction:    os.Getenv(EnvResolveAction),
+		Actions: action.Options{
+			Rules:    actionRules,
+			Cooldown: actionCooldown,
+			DryRun:   os.Getenv(EnvActionDryRun) == "true",
 		},
+		Rollouts: rollouts,
+	}), nil
+}
+
+// kubeconfig builds a client config for the cluster with the configured timeouts and rate limits.
+func kubeconfig(ctx context.Context, cfg aws.Config, cluster string) (*rest.Config, error) {
+	stageTimeout, err := getEnvDuration(EnvStageTimeout, deadline.DefaultStageTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeTimeout, err := getEnvDuration(EnvKubeTimeout, DefaultKubeTimeout)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("Creating event")
+	kubeQPS, err := getEnvInt(EnvKubeQPS, DefaultKubeQPS)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err = clientset.CoreV1().Events(object.ObjectMeta.Namespace).Create(context.TODO(), object, metav1.CreateOptions{})
+	kubeBurst, err := getEnvInt(EnvKubeBurst, DefaultKubeBurst)
 	if err != nil {
-		return fmt.Errorf("failed to create event: %w", err)
+		return nil, err
 	}
 
-	log.Println("Function complete")
+	stageCtx, cancel := deadline.Stage(ctx, stageTimeout)
+	defer cancel()
+
+	config, err := skpreks.BuildKubeconfig(stageCtx, eks.NewFromConfig(cfg), cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+
+	config.Timeout = kubeTimeout
+	config.QPS = float32(kubeQPS)
+	config.Burst = kubeBurst
+
+	tracing.InstrumentKubernetes(config)
+
+	return config, nil
+}
+
+// handleSelfTest checks the IAM and RBAC permissions required to forward alarms.
+func handleSelfTest(ctx context.Context, req selftest.Request) (*selftest.Report, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config, %v", err)
+	}
+
+	tracing.InstrumentAWS(&cfg)
+
+	runner := selftest.New(awscloudwatch.NewFromConfig(cfg), func(ctx context.Context, cluster string) (kubernetes.Interface, error) {
+		config, err := kubeconfig(ctx, cfg, cluster)
+		if err != nil {
+			return nil, err
+		}
+
+		return kubernetes.NewForConfig(config)
+	})
+
+	return runner.Run(ctx, req), nil
+}
+
+// getEnvInt returns an integer from an environment variable, or the fallback if it is not set.
+func getEnvInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+
+	return i, nil
+}
+
+// getEnvDuration returns a duration from an environment variable, or the fallback if it is not set.
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
 
-	return nil
+	return d, nil
 }
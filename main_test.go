@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/batch"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/cloudwatch"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/forwarder"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/logging"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/report"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/resolver"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/sink"
+	"github.com/skpr/lambda-eks-event-cloudwatch/internal/suppress"
+	skpraws "github.com/skpr/lambda-eks-event-cloudwatch/pkg/aws"
+)
+
+// listTagsResponse is the CloudWatch ListTagsForResource response with the tags.
+func listTagsResponse(tags map[string]string) string {
+	var members strings.Builder
+
+	for key, value := range tags {
+		fmt.Fprintf(&members, "<member><Key>%s</Key><Value>%s</Value></member>", key, value)
+	}
+
+	return `<ListTagsForResourceResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <ListTagsForResourceResult>
+    <Tags>` + members.String() + `</Tags>
+  </ListTagsForResourceResult>
+  <ResponseMetadata>
+    <RequestId>test</RequestId>
+  </ResponseMetadata>
+</ListTagsForResourceResponse>`
+}
+
+// setupAWS points the SDK at a fake CloudWatch API which tags every alarm. Alarms are sent to the stdout sink unless
+// the tags select other sinks.
+func setupAWS(t *testing.T, tags map[string]string) {
+	if tags == nil {
+		tags = map[string]string{skpraws.TagKeySinks: sink.NameStdout}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, listTagsResponse(tags))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_REGION", "ap-southeast-2")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv(EnvRulesFile, "")
+}
+
+// sqsRecord wraps the body in an SQS record.
+func sqsRecord(t *testing.T, id string, body any) batch.Record {
+	data, err := json.Marshal(body)
+	assert.NoError(t, err)
+
+	return batch.Record{MessageID: id, EventSource: batch.SourceSQS, Body: string(data)}
+}
+
+func TestHandleLambdaEventBatchItemFailures(t *testing.T) {
+	setupAWS(t, nil)
+
+	alarm := map[string]any{
+		"alarmArn": "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:drupal-5xx",
+		"alarmData": map[string]any{
+			"alarmName": "drupal-5xx",
+			"state":     map[string]any{"value": "ALARM", "timestamp": "2024-01-01T12:00:00.000+0000"},
+			"configuration": map[string]any{
+				"description": "High error rate",
+			},
+		},
+	}
+
+	notification := map[string]any{
+		"AlarmName":        "drupal-5xx",
+		"AlarmDescription": "High error rate",
+		"AlarmArn":         "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:drupal-5xx",
+		"NewStateValue":    "OK",
+		"NewStateReason":   "Threshold Crossed",
+		"StateChangeTime":  "2024-01-01T12:05:00.000+0000",
+		"OldStateValue":    "ALARM",
+	}
+
+	payload := &Payload{
+		Records: []batch.Record{
+			sqsRecord(t, "alarm", alarm),
+			sqsRecord(t, "missing-description", map[string]any{
+				"alarmArn":  "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:drupal-5xx",
+				"alarmData": map[string]any{"alarmName": "drupal-5xx"},
+			}),
+			sqsRecord(t, "notification", map[string]any{
+				"Type":      batch.NotificationType,
+				"MessageId": "sns-1",
+				"Message":   mustMarshal(t, notification),
+			}),
+			{MessageID: "malformed", EventSource: batch.SourceSQS, Body: "not json"},
+		},
+	}
+
+	response, err := HandleLambdaEvent(context.TODO(), payload)
+	assert.NoError(t, err)
+
+	// Only the records which failed are retried.
+	assert.Equal(t, []batch.ItemFailure{
+		{ItemIdentifier: "missing-description"},
+		{ItemIdentifier: "malformed"},
+	}, response.BatchItemFailures)
+
+	failures := make(map[string]string)
+
+	for _, target := range response.Targets {
+		failures[target.Record] = target.Error
+	}
+
+	assert.Equal(t, "", failures["alarm"])
+	assert.Equal(t, "", failures["notification"])
+	assert.Contains(t, failures["missing-description"], "description is required")
+	assert.Contains(t, failures["malformed"], "failed to unmarshal record")
+}
+
+func TestHandleLambdaEventSelectorLimit(t *testing.T) {
+	setupAWS(t, map[string]string{
+		skpraws.TagKeySinks:      sink.NameKubernetes,
+		skpraws.TagKeyCluster:    "skpr-test",
+		skpraws.TagKeyAPIVersion: "v1",
+		skpraws.TagKeyKind:       "Pod",
+		skpraws.TagKeyNamespace:  "test",
+		skpraws.TagKeySelector:   "app=drupal",
+		skpraws.TagKeyReason:     "HighErrorRate",
+	})
+
+	var (
+		podGVK       = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+		namespaceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+		clientset    = fake.NewSimpleClientset()
+	)
+
+	newObject := func(gvk schema.GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+		object := &unstructured.Unstructured{}
+		object.SetGroupVersionKind(gvk)
+		object.SetNamespace(namespace)
+		object.SetName(name)
+		object.SetLabels(map[string]string{"app": "drupal"})
+		return object
+	}
+
+	connect = func(context.Context, aws.Config, string) (*forwarder.Forwarder, error) {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(podGVK, meta.RESTScopeNamespace)
+
+		client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+			newObject(namespaceGVK, "", "test"),
+			newObject(podGVK, "test", "drupal-1"),
+			newObject(podGVK, "test", "drupal-2"),
+		)
+
+		checker, err := suppress.New("")
+		if err != nil {
+			return nil, err
+		}
+
+		return forwarder.New(clientset, resolver.New(client, mapper, resolver.Options{Limit: 1}), forwarder.Options{Checker: checker}), nil
+	}
+	t.Cleanup(func() { connect = newForwarder })
+
+	payload := &Payload{
+		Records: []batch.Record{
+			sqsRecord(t, "alarm", map[string]any{
+				"alarmArn": "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:drupal-5xx",
+				"alarmData": map[string]any{
+					"alarmName":     "drupal-5xx",
+					"state":         map[string]any{"value": "ALARM", "timestamp": "2024-01-01T12:00:00.000+0000"},
+					"configuration": map[string]any{"description": "High error rate"},
+				},
+			}),
+			{MessageID: "malformed", EventSource: batch.SourceSQS, Body: "not json"},
+		},
+	}
+
+	response, err := HandleLambdaEvent(context.TODO(), payload)
+	assert.NoError(t, err)
+
+	// The selector will match too many objects on retry as well, so the alarm is denied instead of being retried.
+	assert.Equal(t, []batch.ItemFailure{{ItemIdentifier: "malformed"}}, response.BatchItemFailures)
+
+	for _, target := range response.Targets {
+		if target.Record != "alarm" {
+			continue
+		}
+
+		assert.Empty(t, target.Error)
+
+		if assert.Len(t, target.Decisions, 1) {
+			assert.Equal(t, report.OutcomeDenied, target.Decisions[0].Outcome)
+			assert.Contains(t, target.Decisions[0].Reason, "selector matched more than 1 objects")
+		}
+	}
+
+	list, err := clientset.CoreV1().Events("test").List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, list.Items)
+}
+
+func TestHandleLambdaEventNotSQS(t *testing.T) {
+	setupAWS(t, nil)
+
+	payload := &Payload{
+		Records: []batch.Record{
+			{EventSource: "aws:sns", SNS: &batch.SNSMessage{MessageID: "sns-1", Type: batch.NotificationType, Message: "not json"}},
+		},
+	}
+
+	// Other invocations can't report partial failures, so the invocation fails.
+	_, err := HandleLambdaEvent(context.TODO(), payload)
+	assert.ErrorContains(t, err, "failed to unmarshal record")
+}
+
+func TestPrepareAlarmInvalidReasonData(t *testing.T) {
+	setupAWS(t, nil)
+
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	event := &cloudwatch.Event{
+		AlarmARN: "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:drupal-5xx",
+		AlarmData: cloudwatch.AlarmData{
+			AlarmName: "drupal-5xx",
+			State: cloudwatch.AlarmDataState{
+				Value:      "ALARM",
+				ReasonData: `{"version": "1.0", "evaluatedDatapoints": [`,
+			},
+			Configuration: cloudwatch.AlarmDataConfiguration{
+				Description: "High error rate",
+			},
+		},
+	}
+
+	// The alarm is forwarded without the datapoint summary.
+	alarms, err := prepareAlarm(logging.WithContext(context.TODO(), logging.New(&buf)), cfg, nil, event)
+	assert.NoError(t, err)
+
+	if assert.Len(t, alarms, 1) {
+		assert.Equal(t, "High error rate", alarms[0].Description)
+		assert.Empty(t, alarms[0].Annotations)
+	}
+
+	assert.Contains(t, buf.String(), "Ignoring invalid reason data")
+}
+
+func mustMarshal(t *testing.T, value any) string {
+	data, err := json.Marshal(value)
+	assert.NoError(t, err)
+
+	return string(data)
+}
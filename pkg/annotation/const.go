@@ -2,3 +2,63 @@ package annotation
 
 // KeyCloudWatchAlarmName is the annotation key for determining which CloudWatch Alarm and even came from.
 const KeyCloudWatchAlarmName = "skpr.io/cloudwatch-alarm-name"
+
+// KeyTargetNotFound is the annotation key for events which were emitted using a fallback because the target object was missing.
+const KeyTargetNotFound = "skpr.io/cloudwatch-target-not-found"
+
+// KeySeverity is the annotation key for the severity of the CloudWatch Alarm which an event came from.
+const KeySeverity = "skpr.io/cloudwatch-alarm-severity"
+
+// KeyMutedUntil is the annotation key on a target object or namespace for muting alarms until an RFC3339 time.
+const KeyMutedUntil = "skpr.io/cloudwatch-alarms-muted-until"
+
+// KeyMutedAlarms is the annotation key on a target object or namespace for muting a comma separated list of alarm names.
+const KeyMutedAlarms = "skpr.io/cloudwatch-alarms-muted"
+
+// KeyFlapState is the annotation key on a target object for tracking recent CloudWatch Alarm transitions.
+const KeyFlapState = "skpr.io/cloudwatch-alarm-flap-state"
+
+// KeyDeliveryLag is the annotation key for the time between the CloudWatch Alarm changing state and the event being created.
+const KeyDeliveryLag = "skpr.io/cloudwatch-alarm-delivery-lag"
+
+// KeyStale is the annotation key for events which were created after the staleness limit.
+const KeyStale = "skpr.io/cloudwatch-alarm-stale"
+
+// KeyAlarmAccounts is the annotation key on a namespace for the comma separated AWS accounts whose alarms may target it.
+const KeyAlarmAccounts = "skpr.io/cloudwatch-alarm-accounts"
+
+// KeyAlarmPrefix is the annotation key on a namespace for the alarm name prefix which alarms targeting it must have.
+const KeyAlarmPrefix = "skpr.io/cloudwatch-alarm-prefix"
+
+// KeyResolvedAt is the annotation key for the RFC3339 time a Warning event was resolved by the alarm returning to OK.
+const KeyResolvedAt = "skpr.io/cloudwatch-alarm-resolved-at"
+
+// KeyActionAt is the annotation key on a target object for the RFC3339 time a remediation action was last executed.
+const KeyActionAt = "skpr.io/cloudwatch-alarm-action-at"
+
+// KeyAction is the annotation key for audit events which records the remediation action executed.
+const KeyAction = "skpr.io/cloudwatch-alarm-action"
+
+// KeyThreshold is the annotation key for the threshold the CloudWatch Alarm evaluated datapoints against.
+const KeyThreshold = "skpr.io/cloudwatch-alarm-threshold"
+
+// KeyDatapoints is the annotation key for the JSON encoded datapoints the CloudWatch Alarm evaluated.
+const KeyDatapoints = "skpr.io/cloudwatch-alarm-datapoints"
+
+// KeyStatistic is the annotation key for the statistic the CloudWatch Alarm evaluated eg. "Sum".
+const KeyStatistic = "skpr.io/cloudwatch-alarm-statistic"
+
+// KeyPeriod is the annotation key for the period in seconds of each datapoint the CloudWatch Alarm evaluated.
+const KeyPeriod = "skpr.io/cloudwatch-alarm-period"
+
+// KeyRolloutAt is the annotation key for the RFC3339 time of the last rollout of the target before the alarm.
+const KeyRolloutAt = "skpr.io/cloudwatch-alarm-rollout-at"
+
+// KeyRolloutRevision is the annotation key for the revision of the last rollout of the target before the alarm.
+const KeyRolloutRevision = "skpr.io/cloudwatch-alarm-rollout-revision"
+
+// KeyRolloutImage is the annotation key for the comma separated container images of the last rollout of the target.
+const KeyRolloutImage = "skpr.io/cloudwatch-alarm-rollout-image"
+
+// KeyRolloutObject is the annotation key for the object which was rolled out eg. "Deployment/drupal".
+const KeyRolloutObject = "skpr.io/cloudwatch-alarm-rollout-object"
@@ -13,6 +13,18 @@ const (
 	TagKeyNamespace = "skpr.io/k8s-event-namespace"
 	// TagKeyName is used to determine the name of the Kubernetes resource.
 	TagKeyName = "skpr.io/k8s-event-name"
+	// TagKeySelector is used to determine the label selector for Kubernetes resources, as an alternative to a name.
+	TagKeySelector = "skpr.io/k8s-event-selector"
+	// TagKeyOwner is used to walk owner references up to the given kind, or to the top-level "controller".
+	TagKeyOwner = "skpr.io/k8s-event-owner"
+	// TagKeyOwnerMode is used to determine if events "replace" (default) or "include" the original object when walking owners.
+	TagKeyOwnerMode = "skpr.io/k8s-event-owner-mode"
 	// TagKeyReason is used to determine the reason for this event.
 	TagKeyReason = "skpr.io/k8s-event-reason"
+	// TagKeySeverity is used to determine the severity of this event.
+	TagKeySeverity = "skpr.io/k8s-event-severity"
+	// TagKeySinks is used to determine the comma separated sinks which the alarm is sent to eg. "kubernetes,slack".
+	TagKeySinks = "skpr.io/k8s-event-sinks"
+	// TagKeyAction is used to determine the remediation action executed against the target eg. "rollout-restart", "scale:+2" or "suspend".
+	TagKeyAction = "skpr.io/k8s-action"
 )